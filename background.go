@@ -7,11 +7,13 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/deanishe/awgo/util"
 )
@@ -59,6 +61,68 @@ func (wf *Workflow) RunInBackground(jobName string, cmd *exec.Cmd) error {
 	return wf.savePid(jobName, cmd.Process.Pid)
 }
 
+// debounceState is the session-cached state of a debounced job.
+type debounceState struct {
+	Query   string    // query of last invocation
+	Time    time.Time // when query was first seen
+	Started bool      // whether job has been started for query
+}
+
+// RunInBackgroundDebounced starts cmd in the background like RunInBackground,
+// but only once query has been unchanged for delay, i.e. after the user has
+// stopped typing. It returns true if the job was started.
+//
+// The query and the time it was first seen are stored in the session cache.
+// Until delay has elapsed, the job is not started and Feedback.Rerun is set,
+// so that Alfred runs the Script Filter again. The job is started at most
+// once per query.
+//
+// If the job is still running for an earlier query, it isn't an error:
+// the job for query is started on a later run, once the old job has exited.
+//
+// Typical wiring in a Script Filter:
+//
+//	if _, err := wf.RunInBackgroundDebounced("search", query, 500*time.Millisecond, cmd); err != nil {
+//		wf.FatalError(err)
+//	}
+//	if wf.IsRunning("search") {
+//		wf.Rerun(0.3)
+//		wf.NewItem("Searching…")
+//	}
+func (wf *Workflow) RunInBackgroundDebounced(jobName, query string, delay time.Duration, cmd *exec.Cmd) (bool, error) {
+	var (
+		st   debounceState
+		name = jobName + ".debounce"
+		now  = time.Now()
+	)
+	if wf.Session.Exists(name) {
+		if err := wf.Session.LoadJSON(name, &st); err != nil {
+			return false, fmt.Errorf("load debounce state for job %q: %w", jobName, err)
+		}
+	}
+	if st.Query != query || st.Time.IsZero() {
+		st = debounceState{Query: query, Time: now}
+	}
+	if st.Started {
+		return false, nil
+	}
+
+	if elapsed := now.Sub(st.Time); elapsed < delay {
+		wf.Rerun((delay - elapsed).Seconds())
+		return false, wf.Session.StoreJSON(name, st)
+	}
+
+	if err := wf.RunInBackground(jobName, cmd); err != nil {
+		if IsJobExists(err) {
+			wf.Rerun(delay.Seconds())
+			return false, wf.Session.StoreJSON(name, st)
+		}
+		return false, err
+	}
+	st.Started = true
+	return true, wf.Session.StoreJSON(name, st)
+}
+
 // Kill stops a background job.
 func (wf *Workflow) Kill(jobName string) error {
 	pid, err := wf.getPid(jobName)
@@ -85,6 +149,44 @@ func (wf *Workflow) IsRunning(jobName string) bool {
 	return true
 }
 
+// Lock acquires an exclusive, inter-process lock called name, e.g. to
+// ensure that only one instance of an operation runs at a time across
+// all the processes Alfred starts. It doesn't block: if another process
+// holds the lock, acquired is false. Otherwise, call unlock to release
+// the lock.
+//
+// Locks are flock(2)-based files in the lock directory of the workflow's
+// cache (the same one Cache uses), so ClearCache doesn't delete them. The operating
+// system releases a lock when the process holding it exits, so a lock
+// held by a process that has died can always be acquired.
+//
+//	unlock, ok := wf.Lock("refresh")
+//	if !ok {
+//		return // another process is refreshing
+//	}
+//	defer unlock()
+func (wf *Workflow) Lock(name string) (unlock func(), acquired bool) {
+	p := filepath.Join(wf.CacheDir(), lockDir, name+".lock")
+	util.MustExist(filepath.Dir(p))
+	f, err := util.LockFile(p, false)
+	if err != nil {
+		if !errors.Is(err, util.ErrLocked) {
+			log.Printf("[ERROR] lock %q: %v", name, err)
+		}
+		return func() {}, false
+	}
+
+	// record holder for debugging
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	return func() {
+		if err := f.Close(); err != nil {
+			log.Printf("[ERROR] unlock %q: %v", name, err)
+		}
+	}, true
+}
+
 // Save PID to a job-specific file.
 func (wf *Workflow) savePid(jobName string, pid int) error {
 	return ioutil.WriteFile(wf.pidFile(jobName), []byte(strconv.Itoa(pid)), 0600)
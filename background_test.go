@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -75,3 +77,105 @@ func TestWorkflow_RunInBackground_badJob(t *testing.T) {
 		assert.NotNil(t, wf.RunInBackground("badJob", cmd), `run "/does/not/exist" succeeded`)
 	})
 }
+
+// Debounced background jobs.
+func TestWorkflow_RunInBackgroundDebounced(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		jobName := "debounced"
+		delay := time.Millisecond * 50
+		run := func(query string) bool {
+			ok, err := wf.RunInBackgroundDebounced(jobName, query, delay, exec.Command("sleep", "5"))
+			require.Nil(t, err, "debounced job failed")
+			return ok
+		}
+		defer wf.Kill(jobName)
+
+		// new query isn't run immediately
+		assert.False(t, run("a"), "job started for new query")
+		assert.NotEqual(t, 0.0, wf.Feedback.rerun, "rerun not set")
+		// query changed
+		time.Sleep(delay)
+		assert.False(t, run("ab"), "job started for changed query")
+		assert.False(t, wf.IsRunning(jobName), "job is running")
+
+		// query unchanged for delay
+		time.Sleep(delay)
+		assert.True(t, run("ab"), "job not started")
+		assert.True(t, wf.IsRunning(jobName), "job is not running")
+
+		// job is only started once per query
+		require.Nil(t, wf.Kill(jobName), "kill job failed")
+		time.Sleep(delay)
+		assert.False(t, run("ab"), "job started twice")
+	})
+}
+
+// A debounced job waits for a running job with the same name.
+func TestWorkflow_RunInBackgroundDebounced_running(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		var (
+			jobName = "debounced"
+			delay   = time.Millisecond * 50
+			cmd     = func() *exec.Cmd { return exec.Command("sleep", "5") }
+		)
+		require.Nil(t, wf.RunInBackground(jobName, cmd()), "start job failed")
+		defer wf.Kill(jobName)
+
+		assert.False(t, runDebounced(t, wf, jobName, "a", delay, cmd()), "job started for new query")
+		time.Sleep(delay)
+		wf.Feedback.rerun = 0
+		assert.False(t, runDebounced(t, wf, jobName, "a", delay, cmd()), "job started while old job running")
+		assert.NotEqual(t, 0.0, wf.Feedback.rerun, "rerun not set")
+
+		// job starts once old job has exited
+		require.Nil(t, wf.Kill(jobName), "kill job failed")
+		assert.True(t, runDebounced(t, wf, jobName, "a", delay, cmd()), "job not started")
+	})
+}
+
+// runDebounced calls RunInBackgroundDebounced and fails t on error.
+func runDebounced(t *testing.T, wf *Workflow, jobName, query string, delay time.Duration, cmd *exec.Cmd) bool {
+	ok, err := wf.RunInBackgroundDebounced(jobName, query, delay, cmd)
+	require.Nil(t, err, "debounced job failed")
+	return ok
+}
+
+// Locks are exclusive
+func TestWorkflow_Lock(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		unlock, ok := wf.Lock("test")
+		require.True(t, ok, "lock not acquired")
+
+		_, ok = wf.Lock("test")
+		assert.False(t, ok, "lock acquired twice")
+		unlock2, ok := wf.Lock("other")
+		assert.True(t, ok, "other lock not acquired")
+		unlock2()
+
+		unlock()
+		unlock, ok = wf.Lock("test")
+		assert.True(t, ok, "released lock not acquired")
+		unlock()
+
+		// lockfile left by a dead process
+		p := filepath.Join(wf.CacheDir(), lockDir, "stale.lock")
+		require.Nil(t, ioutil.WriteFile(p, []byte("999999"), 0600), "write lockfile failed")
+		unlock, ok = wf.Lock("stale")
+		assert.True(t, ok, "stale lock not acquired")
+		unlock()
+
+		// ClearCache doesn't delete held locks
+		unlock, ok = wf.Lock("test")
+		require.True(t, ok, "lock not acquired")
+		require.Nil(t, wf.ClearCache(), "clear cache failed")
+		_, ok = wf.Lock("test")
+		assert.False(t, ok, "lock acquired after ClearCache")
+		unlock()
+	})
+}
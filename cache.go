@@ -5,6 +5,7 @@ package aw
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -24,6 +25,30 @@ var (
 	letters       = []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 )
 
+// ErrCacheMiss is returned by Load and LoadJSON if the named cache
+// doesn't exist. Use errors.Is() to check for it. The returned error
+// also wraps the underlying os.ErrNotExist.
+//
+// Breaking change: previously, Load returned the *os.PathError from
+// os.Stat. os.IsNotExist() doesn't unwrap errors, so it no longer reports
+// a missing cache. Use errors.Is(err, ErrCacheMiss) or
+// errors.Is(err, os.ErrNotExist) instead.
+var ErrCacheMiss = errors.New("cache miss")
+
+// cacheMiss is the error returned for a non-existent cache.
+type cacheMiss struct {
+	err error // error from reading cache file
+}
+
+// Error implements error interface.
+func (err cacheMiss) Error() string { return "cache miss: " + err.err.Error() }
+
+// Is returns true if target is ErrCacheMiss.
+func (err cacheMiss) Is(target error) bool { return target == ErrCacheMiss }
+
+// Unwrap returns the underlying error.
+func (err cacheMiss) Unwrap() error { return err.err }
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
@@ -52,6 +77,11 @@ func NewCache(dir string) *Cache {
 	return &Cache{dir}
 }
 
+// Namespace returns a Cache for the subdirectory name of the cache
+// directory, e.g. to keep unrelated data apart. Calling Clear() or
+// ClearOld() on the returned Cache only affects the subdirectory.
+func (c Cache) Namespace(name string) *Cache { return NewCache(c.path(name)) }
+
 // Store saves data under the given name. If data is nil, the cache is deleted.
 func (c Cache) Store(name string, data []byte) error {
 	p := c.path(name)
@@ -82,19 +112,29 @@ func (c Cache) StoreJSON(name string, v interface{}) error {
 }
 
 // Load reads data saved under given name.
+// It returns an error wrapping ErrCacheMiss if the cache doesn't exist.
+// Check for it with errors.Is, not os.IsNotExist (see ErrCacheMiss).
 func (c Cache) Load(name string) ([]byte, error) {
 	p := c.path(name)
 	if _, err := os.Stat(p); err != nil {
+		if os.IsNotExist(err) {
+			return nil, cacheMiss{err}
+		}
 		return nil, err
 	}
 	return ioutil.ReadFile(p)
 }
 
 // LoadJSON unmarshals named cache into v.
+// It returns an error wrapping ErrCacheMiss if the cache doesn't exist.
+// Check for it with errors.Is, not os.IsNotExist (see ErrCacheMiss).
 func (c Cache) LoadJSON(name string, v interface{}) error {
 	p := c.path(name)
 	data, err := ioutil.ReadFile(p)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return cacheMiss{err}
+		}
 		return fmt.Errorf("read file: %w", err)
 	}
 	return json.Unmarshal(data, v)
@@ -105,7 +145,20 @@ func (c Cache) LoadJSON(name string, v interface{}) error {
 // called, and the returned data are saved to the cache and also returned.
 //
 // If maxAge is 0, any cached data are always returned.
+//
+// If reload fails and (expired) cached data exist, those stale data are
+// returned along with the error, so callers can still show results.
+//
+// The named cache is locked while LoadOrStore runs, so if several
+// processes call LoadOrStore simultaneously, only one of them calls
+// reload and the others wait for (and then load) its result.
 func (c Cache) LoadOrStore(name string, maxAge time.Duration, reload func() ([]byte, error)) ([]byte, error) {
+	unlock, err := c.lock(name)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	var load bool
 	age, err := c.Age(name)
 	if err != nil {
@@ -117,7 +170,11 @@ func (c Cache) LoadOrStore(name string, maxAge time.Duration, reload func() ([]b
 	if load {
 		data, err := reload()
 		if err != nil {
-			return nil, fmt.Errorf("reload data: %w", err)
+			err = fmt.Errorf("reload data: %w", err)
+			if stale, e := c.Load(name); e == nil {
+				return stale, err
+			}
+			return nil, err
 		}
 		if err := c.Store(name, data); err != nil {
 			return nil, err
@@ -133,12 +190,21 @@ func (c Cache) LoadOrStore(name string, maxAge time.Duration, reload func() ([]b
 // cached, and also unmarshalled into v.
 //
 // If maxAge is 0, any cached data are loaded regardless of age.
+//
+// Like LoadOrStore, the named cache is locked while LoadOrStoreJSON runs,
+// and if reload fails, any stale cached data are unmarshalled into v
+// before the error is returned.
 func (c Cache) LoadOrStoreJSON(name string, maxAge time.Duration, reload func() (interface{}, error), v interface{}) error {
 	var (
 		load bool
 		data []byte
-		err  error
 	)
+	unlock, err := c.lock(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	age, err := c.Age(name)
 	if err != nil {
 		load = true
@@ -149,7 +215,13 @@ func (c Cache) LoadOrStoreJSON(name string, maxAge time.Duration, reload func()
 	if load {
 		i, err := reload()
 		if err != nil {
-			return fmt.Errorf("reload data: %w", err)
+			err = fmt.Errorf("reload data: %w", err)
+			if stale, e := c.Load(name); e == nil {
+				if e := json.Unmarshal(stale, v); e != nil {
+					log.Printf("[ERROR] unmarshal stale data %q: %v", name, e)
+				}
+			}
+			return err
 		}
 		data, err = json.MarshalIndent(i, "", "  ")
 		if err != nil {
@@ -190,6 +262,163 @@ func (c Cache) Age(name string) (time.Duration, error) {
 	return time.Since(fi.ModTime()), nil
 }
 
+// Clear deletes all files and directories in the cache directory, but not
+// the directory itself.
+//
+// Clear continues past files it can't delete. The returned error describes
+// all failures and wraps the first one.
+func (c Cache) Clear() error {
+	if !util.PathExists(c.Dir) {
+		return nil
+	}
+	infos, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		return fmt.Errorf("clear cache: %w", err)
+	}
+
+	var errs []error
+	for _, fi := range infos {
+		if fi.Name() == lockDir {
+			continue
+		}
+		if err := os.RemoveAll(c.path(fi.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := joinErrors(errs); err != nil {
+		return fmt.Errorf("clear cache: %w", err)
+	}
+	return nil
+}
+
+// ClearOld deletes files in the cache directory (and its subdirectories)
+// that are older than maxAge. Directories are not deleted.
+//
+// Like Clear, ClearOld continues past files it can't delete and returns
+// an error describing all failures.
+func (c Cache) ClearOld(maxAge time.Duration) error {
+	if !util.PathExists(c.Dir) {
+		return nil
+	}
+
+	var errs []error
+	err := filepath.Walk(c.Dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if fi.IsDir() && fi.Name() == lockDir {
+			return filepath.SkipDir
+		}
+		if fi.IsDir() || time.Since(fi.ModTime()) <= maxAge {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	err = c.clearLocks(func(name string, fi os.FileInfo) bool {
+		return time.Since(fi.ModTime()) > maxAge
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if err := joinErrors(errs); err != nil {
+		return fmt.Errorf("clear old cache files: %w", err)
+	}
+	return nil
+}
+
+// joinErrors combines errs into a single error, which wraps the first one.
+// It returns nil if errs is empty.
+func joinErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	err := errs[0]
+	for _, e := range errs[1:] {
+		err = fmt.Errorf("%w; %v", err, e)
+	}
+	return err
+}
+
+// lockDir is the subdirectory of a cache directory that contains lockfiles.
+// Clear doesn't delete it, as other processes may hold the locks. ClearOld
+// and Session.Clear only delete lockfiles that aren't currently held.
+const lockDir = ".locks"
+
+// lock acquires an exclusive lock on the named cache, blocking until
+// the lock is available. Call the returned function to release it.
+func (c Cache) lock(name string) (func(), error) {
+	p := c.lockPath(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return nil, fmt.Errorf("create lock directory: %w", err)
+	}
+	f, err := util.LockFile(p, true)
+	if err != nil {
+		return nil, fmt.Errorf("lock %q: %w", name, err)
+	}
+	return func() {
+		if err := f.Close(); err != nil {
+			log.Printf("[ERROR] unlock %q: %v", name, err)
+		}
+	}, nil
+}
+
+// lockPath returns the path of the lockfile for the named cache.
+func (c Cache) lockPath(name string) string {
+	return filepath.Join(c.path(lockDir), name+".lock")
+}
+
+// clearLocks deletes the lockfiles for which match returns true. match
+// is passed the name of the cache the lockfile belongs to. Lockfiles
+// that are currently held by another process are left alone.
+func (c Cache) clearLocks(match func(name string, fi os.FileInfo) bool) error {
+	dir := c.path(lockDir)
+	if !util.PathExists(dir) {
+		return nil
+	}
+
+	var errs []error
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if fi.IsDir() || !strings.HasSuffix(p, ".lock") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if !match(strings.TrimSuffix(filepath.ToSlash(rel), ".lock"), fi) {
+			return nil
+		}
+		f, err := util.LockFile(p, false)
+		if err != nil {
+			if err != util.ErrLocked {
+				errs = append(errs, err)
+			}
+			return nil
+		}
+		defer f.Close()
+		if err := os.Remove(p); err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return joinErrors(errs)
+}
+
 // path returns the path to a named file within cache directory.
 func (c Cache) path(name string) string { return filepath.Join(c.Dir, name) }
 
@@ -252,6 +481,16 @@ func (s Session) Clear(current bool) error {
 		os.RemoveAll(p)
 		log.Printf("deleted %s", p)
 	}
+
+	err = s.cache.clearLocks(func(name string, _ os.FileInfo) bool {
+		if !strings.HasPrefix(name, prefix) {
+			return false
+		}
+		return current || !strings.HasPrefix(name, curPrefix)
+	})
+	if err != nil {
+		return fmt.Errorf("clear session locks: %w", err)
+	}
 	return nil
 }
 
@@ -5,7 +5,11 @@ package aw
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -65,6 +69,12 @@ func TestCache_Store(t *testing.T) {
 		// Load non-existent cache
 		_, err = c.Load(n)
 		assert.NotNil(t, err, "load non-existent data succeeded")
+		assert.True(t, errors.Is(err, ErrCacheMiss), "not a cache miss: %v", err)
+		assert.True(t, errors.Is(err, os.ErrNotExist), "doesn't wrap os.ErrNotExist: %v", err)
+
+		var v interface{}
+		err = c.LoadJSON(n, &v)
+		assert.True(t, errors.Is(err, ErrCacheMiss), "not a cache miss: %v", err)
 	})
 }
 
@@ -172,6 +182,45 @@ func TestCache_StoreJSON(t *testing.T) {
 	})
 }
 
+// TestCache_LoadOrStoreConcurrent verifies that concurrent calls to
+// LoadOrStore only call reload once.
+func TestCache_LoadOrStoreConcurrent(t *testing.T) {
+	t.Parallel()
+
+	withTempDir(func(dir string) {
+		var (
+			n       = "test.txt"
+			c       = NewCache(dir)
+			data    = []byte("expensive data")
+			reloads int32
+			wg      sync.WaitGroup
+		)
+
+		reload := func() ([]byte, error) {
+			atomic.AddInt32(&reloads, 1)
+			time.Sleep(time.Millisecond * 20)
+			return data, nil
+		}
+
+		results := make([][]byte, 20)
+		errs := make([]error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], errs[i] = c.LoadOrStore(n, 0, reload)
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), reloads, "reload called more than once")
+		for i := range results {
+			require.Nil(t, errs[i], "LoadOrStore failed")
+			assert.Equal(t, data, results[i], "unexpected data")
+		}
+	})
+}
+
 // TestLoadOrStoreJSON tests JSON serialisation.
 func TestCache_LoadOrStoreJSON(t *testing.T) {
 	t.Parallel()
@@ -254,6 +303,156 @@ func TestCache_reloadError(t *testing.T) {
 	})
 }
 
+// Stale data are returned if reload fails.
+func TestCache_reloadErrorStale(t *testing.T) {
+	t.Parallel()
+
+	reloadB := func() ([]byte, error) {
+		return nil, errors.New("an error")
+	}
+
+	reloadJSON := func() (interface{}, error) {
+		return nil, errors.New("an error")
+	}
+
+	withTempDir(func(dir string) {
+		var (
+			c     = NewCache(dir)
+			n     = "test.txt"
+			nJSON = "test.json"
+			data  = []byte("stale data")
+			a     = &TestData{"one", "two"}
+		)
+		require.Nil(t, c.Store(n, data), "store data failed")
+		require.Nil(t, c.StoreJSON(nJSON, a), "store JSON failed")
+		time.Sleep(time.Millisecond * 10)
+
+		b, err := c.LoadOrStore(n, time.Millisecond, reloadB)
+		assert.NotNil(t, err, "no error returned by reloadB")
+		assert.Equal(t, data, b, "stale data not returned")
+
+		v := &TestData{}
+		assert.NotNil(t, c.LoadOrStoreJSON(nJSON, time.Millisecond, reloadJSON, v), "no error returned by reloadJSON")
+		assert.Equal(t, a, v, "stale data not loaded")
+	})
+}
+
+// Clear deletes cache contents but not the cache directory
+func TestCache_Clear(t *testing.T) {
+	t.Parallel()
+
+	withTempDir(func(dir string) {
+		c := NewCache(dir)
+		require.Nil(t, c.Store("one.txt", []byte("one")), "store failed")
+		require.Nil(t, c.Store("two.txt", []byte("two")), "store failed")
+		util.MustExist(c.path("subdir"))
+
+		require.Nil(t, c.Clear(), "clear failed")
+		assert.True(t, util.PathExists(dir), "cache directory deleted")
+		infos, err := ioutil.ReadDir(dir)
+		require.Nil(t, err, "read cache directory failed")
+		assert.Equal(t, 0, len(infos), "cache not empty")
+	})
+
+	// non-existent directory is ignored
+	c := &Cache{Dir: "/does/not/exist"}
+	assert.Nil(t, c.Clear(), "clear non-existent cache failed")
+}
+
+// Lockfiles are kept out of the way of Clear and ClearOld
+func TestCache_lock(t *testing.T) {
+	t.Parallel()
+
+	withTempDir(func(dir string) {
+		c := NewCache(dir)
+		unlock, err := c.lock("test.txt")
+		require.Nil(t, err, "lock failed")
+		defer unlock()
+		p := filepath.Join(dir, lockDir, "test.txt.lock")
+		assert.True(t, util.PathExists(p), "lockfile not created")
+		assert.False(t, util.PathExists(c.path("test.txt.lock")), "lockfile in cache directory")
+
+		require.Nil(t, c.ClearOld(0), "clear old failed")
+		assert.True(t, util.PathExists(p), "ClearOld deleted lockfile")
+		require.Nil(t, c.Clear(), "clear failed")
+		assert.True(t, util.PathExists(p), "Clear deleted lockfile")
+
+		// names may contain subdirectories
+		unlockSub, err := c.lock("sub/test.txt")
+		require.Nil(t, err, "lock subdirectory name failed")
+		unlockSub()
+		p2 := filepath.Join(dir, lockDir, "sub", "test.txt.lock")
+		assert.True(t, util.PathExists(p2), "lockfile not created")
+
+		// unheld old lockfiles are deleted
+		old := time.Now().Add(-time.Hour)
+		require.Nil(t, os.Chtimes(p, old, old), "chtimes failed")
+		require.Nil(t, os.Chtimes(p2, old, old), "chtimes failed")
+		require.Nil(t, c.ClearOld(time.Minute), "clear old failed")
+		assert.True(t, util.PathExists(p), "ClearOld deleted held lockfile")
+		assert.False(t, util.PathExists(p2), "ClearOld didn't delete old lockfile")
+	})
+}
+
+// Namespaces are subdirectories of the cache
+func TestCache_Namespace(t *testing.T) {
+	t.Parallel()
+
+	withTempDir(func(dir string) {
+		var (
+			c     = NewCache(dir)
+			feeds = c.Namespace("feeds")
+		)
+		assert.Equal(t, filepath.Join(dir, "feeds"), feeds.Dir, "unexpected directory")
+		assert.True(t, util.PathExists(feeds.Dir), "namespace directory not created")
+
+		require.Nil(t, c.Store("root.txt", []byte("root")), "store failed")
+		require.Nil(t, feeds.Store("feed.txt", []byte("feed")), "store failed")
+		assert.True(t, c.Exists("feeds/feed.txt"), "namespaced file not in subdirectory")
+
+		require.Nil(t, feeds.Clear(), "clear namespace failed")
+		assert.False(t, feeds.Exists("feed.txt"), "namespace not cleared")
+		assert.True(t, c.Exists("root.txt"), "parent cache cleared")
+	})
+}
+
+// ClearOld only deletes old files
+func TestCache_ClearOld(t *testing.T) {
+	t.Parallel()
+
+	withTempDir(func(dir string) {
+		var (
+			c   = NewCache(dir)
+			sub = util.MustExist(c.path("subdir"))
+			old = time.Now().Add(-time.Hour)
+		)
+		for _, name := range []string{"old.txt", "new.txt", "subdir/old.txt"} {
+			require.Nil(t, c.Store(name, []byte(name)), "store failed")
+		}
+		for _, name := range []string{"old.txt", "subdir/old.txt"} {
+			require.Nil(t, os.Chtimes(c.path(name), old, old), "chtimes failed")
+		}
+
+		require.Nil(t, c.ClearOld(time.Minute), "clear old failed")
+		assert.False(t, c.Exists("old.txt"), "old file not deleted")
+		assert.False(t, c.Exists("subdir/old.txt"), "old file in subdirectory not deleted")
+		assert.True(t, c.Exists("new.txt"), "new file deleted")
+		assert.True(t, util.PathExists(sub), "subdirectory deleted")
+	})
+}
+
+// Multiple errors are combined
+func TestJoinErrors(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, joinErrors(nil), "empty errors not nil")
+
+	first := errors.New("first")
+	err := joinErrors([]error{first, errors.New("second")})
+	assert.Equal(t, "first; second", err.Error(), "unexpected message")
+	assert.True(t, errors.Is(err, first), "first error not wrapped")
+}
+
 // Session-scoped caching.
 func TestSession_Load(t *testing.T) {
 	t.Parallel()
@@ -438,14 +637,29 @@ func TestSession_Clear(t *testing.T) {
 			n    = "test.txt"
 		)
 
+		reload := func() ([]byte, error) { return data, nil }
+
 		// "old" session
 		s := NewSession(dir, NewSessionID())
-		assert.Nil(t, s.Store(n, data), "store failed")
+		_, err := s.LoadOrStore(n, reload)
+		assert.Nil(t, err, "store failed")
 		assert.True(t, s.Exists(n), "cached data do not exist")
+		oldLock := s.cache.lockPath(s.name(n))
+		assert.True(t, util.PathExists(oldLock), "lockfile not created")
 
 		// "new" session
 		s = NewSession(dir, NewSessionID())
+		_, err = s.LoadOrStore(n, reload)
+		assert.Nil(t, err, "store failed")
+		curLock := s.cache.lockPath(s.name(n))
+
 		assert.Nil(t, s.Clear(false), "clear failed")
-		assert.False(t, s.Exists(n), "expired data still exist")
+		assert.False(t, util.PathExists(oldLock), "expired lockfile still exists")
+		assert.True(t, s.Exists(n), "current data deleted")
+		assert.True(t, util.PathExists(curLock), "current lockfile deleted")
+
+		assert.Nil(t, s.Clear(true), "clear failed")
+		assert.False(t, s.Exists(n), "current data still exist")
+		assert.False(t, util.PathExists(curLock), "current lockfile still exists")
 	})
 }
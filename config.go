@@ -4,8 +4,14 @@
 package aw
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -51,6 +57,60 @@ const (
 	EnvVarLocalhash = "alfred_preferences_localhash"
 )
 
+// SecretVarPattern matches the names of variables whose values are
+// redacted by Config.Dump(). Names are matched on whole segments separated
+// by "_", "-" or ".", so "DB_PASS" and "auth_token" match, but "author"
+// and "passenger" don't.
+var SecretVarPattern = regexp.MustCompile(`(?i)(^|[_.-])(tokens?|secrets?|pass|passwd|passwords?|pwd|auth|api[_.-]?keys?)($|[_.-])`)
+
+// systemVars are variables of the process environment that aren't set by
+// Alfred or the workflow. Config.Dump() omits them.
+var systemVars = map[string]bool{
+	"_":                    true,
+	"COMMAND_MODE":         true,
+	"DISPLAY":              true,
+	"EDITOR":               true,
+	"HOME":                 true,
+	"LANG":                 true,
+	"LANGUAGE":             true,
+	"LOGNAME":              true,
+	"MAIL":                 true,
+	"OLDPWD":               true,
+	"PATH":                 true,
+	"PWD":                  true,
+	"SHELL":                true,
+	"SHLVL":                true,
+	"SSH_AUTH_SOCK":        true,
+	"TERM":                 true,
+	"TERM_PROGRAM":         true,
+	"TERM_PROGRAM_VERSION": true,
+	"TERM_SESSION_ID":      true,
+	"TMPDIR":               true,
+	"USER":                 true,
+}
+
+// systemVarPrefixes are prefixes of system variables, e.g. set by macOS.
+var systemVarPrefixes = []string{"__", "Apple_", "LC_", "XPC_"}
+
+// isSystemVar returns true if key is the name of a system variable.
+func isSystemVar(key string) bool {
+	if strings.HasPrefix(key, "alfred_") {
+		return false
+	}
+	if systemVars[key] {
+		return true
+	}
+	for _, s := range systemVarPrefixes {
+		if strings.HasPrefix(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redacted replaces the values of secret variables in Config.Dump().
+const redacted = "<redacted>"
+
 // mockable JS script runner
 var runJS = func(script string) error {
 	_, err := util.RunJS(script)
@@ -78,6 +138,7 @@ type Config struct {
 	Env
 	reader  env.Reader
 	scripts []string
+	pending map[string]*string // Set/Unset changes to apply after Do(). nil = unset
 }
 
 // NewConfig creates a new Config from the environment.
@@ -136,9 +197,27 @@ func (cfg *Config) GetFloat(key string, fallback ...float64) float64 {
 // It accepts one optional "fallback" argument. If no envvar is set, returns
 // fallback or 0.
 //
-// Values are parsed with time.ParseDuration().
+// Values are parsed with time.ParseDuration(). A bare integer is treated
+// as a number of seconds, e.g. "30" is 30s. If the value can't be parsed,
+// a warning is logged and fallback (or 0) is returned.
 func (cfg *Config) GetDuration(key string, fallback ...time.Duration) time.Duration {
-	return cfg.reader.GetDuration(key, fallback...)
+	var d time.Duration
+	if len(fallback) > 0 {
+		d = fallback[0]
+	}
+	s, ok := cfg.Lookup(key)
+	if !ok {
+		return d
+	}
+	s = strings.TrimSpace(s)
+	if v, err := time.ParseDuration(s); err == nil {
+		return v
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(n) * time.Second
+	}
+	log.Printf("[warning] invalid duration for %q: %q", key, s)
+	return d
 }
 
 // GetBool returns the value for envvar "key" as a boolean.
@@ -150,6 +229,245 @@ func (cfg *Config) GetBool(key string, fallback ...bool) bool {
 	return cfg.reader.GetBool(key, fallback...)
 }
 
+// GetStringSlice returns the value for envvar "key" split on sep.
+// Whitespace is trimmed from each element and empty elements are dropped.
+// If no envvar is set, returns fallback.
+func (cfg *Config) GetStringSlice(key, sep string, fallback []string) []string {
+	s, ok := cfg.Lookup(key)
+	if !ok {
+		return fallback
+	}
+	values := []string{}
+	for _, v := range strings.Split(s, sep) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// GetJSON unmarshals the JSON value of envvar "key" into v, which must be a
+// pointer. If no envvar is set, v is left untouched and nil is returned,
+// so v may be pre-populated with default values.
+//
+// An error is returned if the value is not valid JSON.
+func (cfg *Config) GetJSON(key string, v interface{}) error {
+	s, ok := cfg.Lookup(key)
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		return fmt.Errorf("invalid JSON in %q: %w", key, err)
+	}
+	return nil
+}
+
+// StringMap returns all variables whose names start with prefix, with
+// prefix removed from the keys of the returned map. For example, with
+// prefix "feed_1_", the variables "feed_1_url" and "feed_1_name" are
+// returned as "url" and "name".
+//
+// See Dump() for which variables Config can enumerate.
+func (cfg *Config) StringMap(prefix string) map[string]string {
+	m := map[string]string{}
+	for _, k := range cfg.keys() {
+		if strings.HasPrefix(k, prefix) {
+			m[k[len(prefix):]], _ = cfg.Lookup(k)
+		}
+	}
+	return m
+}
+
+// AlfredVersion returns the version of Alfred running the workflow,
+// e.g. "4.5.1", or an empty string if it isn't set.
+func (cfg *Config) AlfredVersion() string { return cfg.Get(EnvVarAlfredVersion) }
+
+// AlfredBuild returns the build number of Alfred running the workflow,
+// or 0 if it isn't set.
+func (cfg *Config) AlfredBuild() int { return cfg.GetInt(EnvVarAlfredBuild) }
+
+// Theme returns the ID of the user's selected theme, e.g.
+// "theme.bundled.dark", or an empty string if it isn't set.
+func (cfg *Config) Theme() string { return cfg.Get(EnvVarTheme) }
+
+// IsDarkTheme returns true if the user's theme is dark.
+//
+// The theme's background colour is used if it can be parsed, otherwise
+// the theme is considered dark if its ID contains "dark". Returns false
+// if no theme info is set.
+func (cfg *Config) IsDarkTheme() bool {
+	var (
+		r, g, b int
+		a       float64
+		s       = strings.Replace(cfg.Get(EnvVarThemeBG), " ", "", -1)
+	)
+	if _, err := fmt.Sscanf(s, "rgba(%d,%d,%d,%f)", &r, &g, &b, &a); err == nil {
+		// perceived brightness
+		return 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) < 128
+	}
+	return strings.Contains(strings.ToLower(cfg.Theme()), "dark")
+}
+
+// Dump returns Alfred's and the workflow's variables, e.g. to log them when
+// debugging. The values of variables whose names match SecretVarPattern
+// are redacted.
+//
+// If Config was created from a map (env.MapEnv), its variables are returned.
+// Otherwise, the variables in the process environment are returned. System
+// variables such as PATH and HOME are omitted in both cases.
+func (cfg *Config) Dump() map[string]string {
+	m := map[string]string{}
+	for _, k := range cfg.keys() {
+		if isSystemVar(k) {
+			continue
+		}
+		v, _ := cfg.Lookup(k)
+		if v != "" && SecretVarPattern.MatchString(k) {
+			v = redacted
+		}
+		m[k] = v
+	}
+	return m
+}
+
+// EnvChange is a difference between a variable's value in Config and in
+// a snapshot. It is returned by Config.Diff().
+type EnvChange struct {
+	Key     string
+	Old     string // Value in snapshot
+	New     string // Value in Config
+	Added   bool   // Variable is not in snapshot
+	Removed bool   // Variable is not in Config
+}
+
+// Diff compares Config's variables to snapshot, typically the saved output
+// of an earlier call to Dump(). Changes are sorted by variable name.
+func (cfg *Config) Diff(snapshot map[string]string) []EnvChange {
+	var (
+		changes []EnvChange
+		current = cfg.Dump()
+		keys    []string
+	)
+	for k := range current {
+		keys = append(keys, k)
+	}
+	for k := range snapshot {
+		if _, ok := current[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v, ok := current[k]
+		old, inSnapshot := snapshot[k]
+		switch {
+		case !inSnapshot:
+			changes = append(changes, EnvChange{Key: k, New: v, Added: true})
+		case !ok:
+			changes = append(changes, EnvChange{Key: k, Old: old, Removed: true})
+		case v != old:
+			changes = append(changes, EnvChange{Key: k, Old: old, New: v})
+		}
+	}
+	return changes
+}
+
+// overrideEnv is an Env whose variables take precedence over those of
+// the Env it wraps.
+type overrideEnv struct {
+	vars  env.MapEnv
+	unset map[string]bool // variables removed from the wrapped Env
+	Env
+}
+
+// Lookup implements Env.
+func (e overrideEnv) Lookup(key string) (string, bool) {
+	if v, ok := e.vars[key]; ok {
+		return v, true
+	}
+	if e.unset[key] {
+		return "", false
+	}
+	return e.Env.Lookup(key)
+}
+
+// override sets variables that take precedence over Config's environment
+// and hides the variables in unset. If Config's environment is already
+// overridden, the changes are merged into the existing overrides.
+func (cfg *Config) override(vars map[string]string, unset map[string]bool) {
+	e, ok := cfg.Env.(overrideEnv)
+	if !ok {
+		e = overrideEnv{Env: cfg.Env}
+	}
+	merged := overrideEnv{
+		vars:  make(env.MapEnv, len(e.vars)+len(vars)),
+		unset: make(map[string]bool, len(e.unset)+len(unset)),
+		Env:   e.Env,
+	}
+	for k, v := range e.vars {
+		merged.vars[k] = v
+	}
+	for k := range e.unset {
+		merged.unset[k] = true
+	}
+	for k, v := range vars {
+		merged.vars[k] = v
+		delete(merged.unset, k)
+	}
+	for k := range unset {
+		delete(merged.vars, k)
+		merged.unset[k] = true
+	}
+	cfg.Env = merged
+	cfg.reader = env.New(cfg.Env)
+}
+
+// keys returns the sorted names of all variables set in Config's Env.
+func (cfg *Config) keys() []string {
+	var (
+		keys []string
+		seen = map[string]bool{}
+	)
+	for _, k := range envKeys(cfg.Env) {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// envKeys returns the names of the variables set in e. It may contain
+// duplicates.
+func envKeys(e Env) []string {
+	var keys []string
+	switch e := e.(type) {
+	case env.MapEnv:
+		for k := range e {
+			keys = append(keys, k)
+		}
+	case overrideEnv:
+		for k := range e.vars {
+			keys = append(keys, k)
+		}
+		for _, k := range envKeys(e.Env) {
+			if !e.unset[k] {
+				keys = append(keys, k)
+			}
+		}
+	default:
+		for _, s := range os.Environ() {
+			k := strings.SplitN(s, "=", 2)[0]
+			if _, ok := e.Lookup(k); ok {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
 // Set saves a workflow variable to info.plist.
 //
 // It accepts one optional bundleID argument, which is the bundle ID of the
@@ -163,6 +481,7 @@ func (cfg *Config) Set(key, value string, export bool, bundleID ...string) *Conf
 		"exportable": export,
 	}
 
+	cfg.addPending(bid, key, &value)
 	return cfg.addScript(scriptSetConfig, key, opts)
 }
 
@@ -177,6 +496,7 @@ func (cfg *Config) Unset(key string, bundleID ...string) *Config {
 		"inWorkflow": bid,
 	}
 
+	cfg.addPending(bid, key, nil)
 	return cfg.addScript(scriptRmConfig, key, opts)
 }
 
@@ -185,16 +505,74 @@ func (cfg *Config) Unset(key string, bundleID ...string) *Config {
 // Returns an error if there are no commands to run, or if the call to Alfred fails.
 // Succeed or fail, any accumulated scripts and errors are cleared when Do()
 // is called.
+//
+// If the call succeeds, Config also applies the changes to the current
+// workflow's variables to itself, so Get() etc. return the new values
+// for the rest of the run.
 func (cfg *Config) Do() error {
 	if len(cfg.scripts) == 0 {
 		return errors.New("no commands to run")
 	}
 
 	script := strings.Join(cfg.scripts, "\n")
+	pending := cfg.pending
 	// reset
 	cfg.scripts = []string{}
+	cfg.pending = nil
+
+	if err := runJS(script); err != nil {
+		return err
+	}
+	cfg.apply(pending)
+	return nil
+}
+
+// Export saves the variables changed with Set() and Unset() to
+// info.plist and updates Config, so subsequent calls to Get() etc.
+// return the new values. Unlike Do(), it is not an error if there
+// are no changes.
+//
+//	n := cfg.GetInt("counter")
+//	if err := cfg.Set("counter", strconv.Itoa(n+1), false).Export(); err != nil {
+//		// handle error
+//	}
+//	cfg.GetInt("counter") // n+1
+func (cfg *Config) Export() error {
+	if len(cfg.scripts) == 0 {
+		return nil
+	}
+	return cfg.Do()
+}
+
+// addPending records a change to a variable made by Set or Unset. value is
+// nil if the variable is unset. Changes to other workflows are ignored.
+func (cfg *Config) addPending(bundleID, key string, value *string) {
+	if bundleID != cfg.getBundleID() {
+		return
+	}
+	if cfg.pending == nil {
+		cfg.pending = map[string]*string{}
+	}
+	cfg.pending[key] = value
+}
 
-	return runJS(script)
+// apply updates Config with changes recorded by addPending.
+func (cfg *Config) apply(pending map[string]*string) {
+	if len(pending) == 0 {
+		return
+	}
+	var (
+		vars  = env.MapEnv{}
+		unset = map[string]bool{}
+	)
+	for k, v := range pending {
+		if v == nil {
+			unset[k] = true
+		} else {
+			vars[k] = *v
+		}
+	}
+	cfg.override(vars, unset)
 }
 
 // Extract bundle ID from argument or default.
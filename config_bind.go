@@ -4,16 +4,112 @@
 package aw
 
 import (
+	"fmt"
+	"reflect"
 	"sort"
+	"strconv"
+	"time"
 
 	"go.deanishe.net/env"
 )
 
 // To populates (tagged) struct v with values from the environment.
+//
+// In addition to the tags supported by deanishe/go-env, To accepts a
+// `default` tag, which sets the value of a field that isn't set in the
+// environment and has its zero value, e.g.:
+//
+//	type Options struct {
+//		Token   string        `env:"API_TOKEN"`
+//		Timeout time.Duration `default:"10s"`
+//	}
+//
+// Defaults are supported for string, bool, int, uint, float and
+// time.Duration fields. If any defaults are invalid, the returned error
+// lists all of them.
 func (cfg *Config) To(v interface{}) error {
+	if err := setDefaults(v); err != nil {
+		return err
+	}
 	return env.Bind(v, cfg)
 }
 
+// setDefaults sets zero-valued fields of struct v to the value of their
+// `default` tag.
+func setDefaults(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil // let env.Bind report the error
+	}
+	rv = rv.Elem()
+
+	var (
+		rt   = rv.Type()
+		errs []error
+	)
+	for i := 0; i < rt.NumField(); i++ {
+		var (
+			f  = rt.Field(i)
+			fv = rv.Field(i)
+		)
+		s, ok := f.Tag.Lookup("default")
+		if !ok || !fv.CanSet() || !fv.IsZero() {
+			continue
+		}
+		if err := setValue(fv, s); err != nil {
+			errs = append(errs, fmt.Errorf("field %s: invalid default %q: %v", f.Name, s, err))
+		}
+	}
+	if err := joinErrors(errs); err != nil {
+		return fmt.Errorf("set defaults: %w", err)
+	}
+	return nil
+}
+
+// setValue parses s into the type of v and sets v to the result.
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
+
 // From saves the fields of (tagged) struct v to the workflow's settings in Alfred.
 // All supported and unignored fields are saved by default. The behaviour can be
 // customised by passing in options from deanishe/go-env, such as env.IgnoreZeroValues
@@ -118,6 +118,45 @@ func TestConfig_To(t *testing.T) {
 	assert.Equal(t, testPingAverage, h.PingAverage, "unexpected PingAverage")
 }
 
+// Defaults are applied to fields not set in the environment.
+func TestConfig_To_defaults(t *testing.T) {
+	t.Parallel()
+
+	type options struct {
+		Host    string        `env:"HOST" default:"localhost"`
+		Port    uint          `default:"8080"`
+		Debug   bool          `default:"true"`
+		Ratio   float64       `default:"0.5"`
+		Timeout time.Duration `default:"10s"`
+		Retries int           `default:"3"`
+		Name    string        `default:"default name"`
+	}
+
+	cfg := NewConfig(env.MapEnv{"HOST": "example.com", "RETRIES": "5"})
+	opts := &options{Name: "set name"}
+	require.Nil(t, cfg.To(opts), "cfg.To failed")
+	assert.Equal(t, &options{
+		Host:    "example.com",
+		Port:    8080,
+		Debug:   true,
+		Ratio:   0.5,
+		Timeout: 10 * time.Second,
+		Retries: 5,
+		Name:    "set name",
+	}, opts, "unexpected options")
+
+	// invalid defaults are all reported
+	type invalid struct {
+		Port    int           `default:"eighty"`
+		Timeout time.Duration `default:"soon"`
+		Debug   bool          `default:"true"`
+	}
+	err := cfg.To(&invalid{})
+	require.NotNil(t, err, "invalid defaults accepted")
+	assert.Contains(t, err.Error(), "field Port", "Port error not reported")
+	assert.Contains(t, err.Error(), "field Timeout", "Timeout error not reported")
+}
+
 // generated script
 func TestConfig_Do(t *testing.T) {
 	orig := runJS
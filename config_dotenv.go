@@ -0,0 +1,98 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DotEnvFile is the name of the file in the workflow's root directory
+// that Workflow.ConfigFromEnv reads variables from.
+const DotEnvFile = ".env"
+
+// ConfigFromEnv overrides the workflow's configuration with the variables
+// in the .env file in its root directory (see Dir()), so you can try out
+// different settings during development without changing them in Alfred.
+// Values in the file take precedence over Alfred's variables for the rest
+// of the run, including the environment passed to RunScript.
+//
+// The file is only read if Alfred's debugger is open, and it is not an
+// error if it doesn't exist, so ConfigFromEnv does nothing in a released
+// workflow. Don't forget to exclude the file from your workflow's releases.
+//
+// The file contains one KEY=VALUE pair per line. Blank lines and lines
+// starting with "#" are ignored, as is an "export " prefix. Values may be
+// enclosed in single or double quotes; double-quoted values are unescaped
+// like Go strings:
+//
+//	# API settings for testing
+//	API_URL=http://localhost:8080
+//	export API_TOKEN="not a real token"
+func (wf *Workflow) ConfigFromEnv() error {
+	if !wf.Debug() {
+		return nil
+	}
+	path := filepath.Join(wf.Dir(), DotEnvFile)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	vars, err := parseDotEnv(f)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	wf.debugf("loaded %d variable(s) from %s", len(vars), path)
+	wf.Config.override(vars, nil)
+	return nil
+}
+
+// parseDotEnv reads KEY=VALUE pairs from r.
+func parseDotEnv(r io.Reader) (map[string]string, error) {
+	var (
+		vars    = map[string]string{}
+		scanner = bufio.NewScanner(r)
+		n       int
+	)
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: not a KEY=VALUE pair: %q", n, line)
+		}
+		k, v := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if len(v) > 1 {
+			switch {
+			case v[0] == '"' && v[len(v)-1] == '"':
+				s, err := strconv.Unquote(v)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid value for %s: %w", n, k, err)
+				}
+				v = s
+			case v[0] == '\'' && v[len(v)-1] == '\'':
+				v = v[1 : len(v)-1]
+			}
+		}
+		vars[k] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
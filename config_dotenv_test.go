@@ -0,0 +1,71 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.deanishe.net/env"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in  string
+		x   map[string]string
+		err bool
+	}{
+		{"", map[string]string{}, false},
+		{"# comment\n\nA=1\n", map[string]string{"A": "1"}, false},
+		{"export A = 1 ", map[string]string{"A": "1"}, false},
+		{"A=\nB==", map[string]string{"A": "", "B": "="}, false},
+		{`A="two\nlines"`, map[string]string{"A": "two\nlines"}, false},
+		{`A='not\nescaped'`, map[string]string{"A": `not\nescaped`}, false},
+		{`A="`, map[string]string{"A": `"`}, false},
+		{"A=1\nA=2", map[string]string{"A": "2"}, false},
+		{"A", nil, true},
+		{"=1", nil, true},
+		{`A="bad\q"`, nil, true},
+	}
+	for _, td := range tests {
+		v, err := parseDotEnv(strings.NewReader(td.in))
+		if td.err {
+			assert.NotNil(t, err, "invalid input %q accepted", td.in)
+			continue
+		}
+		require.Nil(t, err, "parse %q failed", td.in)
+		assert.Equal(t, td.x, v, "unexpected variables for %q", td.in)
+	}
+}
+
+func TestWorkflow_ConfigFromEnv(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		wf.dir = wf.DataDir()
+		// no file
+		require.Nil(t, wf.ConfigFromEnv(), "ConfigFromEnv failed")
+
+		data := "API_URL=http://localhost\n" + EnvVarName + "=Overridden\n"
+		require.Nil(t, ioutil.WriteFile(filepath.Join(wf.dir, DotEnvFile), []byte(data), 0600), "write .env failed")
+		require.Nil(t, wf.ConfigFromEnv(), "ConfigFromEnv failed")
+		assert.Equal(t, "http://localhost", wf.Config.Get("API_URL"), "unexpected API_URL")
+		assert.Equal(t, "Overridden", wf.Config.Get(EnvVarName), "unexpected name")
+		assert.Equal(t, tBundleID, wf.Config.Get(EnvVarBundleID), "unexpected bundle ID")
+		assert.Contains(t, wf.scriptEnv(), "API_URL=http://localhost", "variable not passed to scripts")
+
+		require.Nil(t, ioutil.WriteFile(filepath.Join(wf.dir, DotEnvFile), []byte("invalid"), 0600), "write .env failed")
+		assert.NotNil(t, wf.ConfigFromEnv(), "invalid .env accepted")
+
+		// file is ignored if debugger isn't open
+		wf.Config = NewConfig(env.MapEnv{EnvVarDebug: "0"})
+		assert.Nil(t, wf.ConfigFromEnv(), ".env read with debugger closed")
+	})
+}
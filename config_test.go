@@ -4,12 +4,15 @@
 package aw
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.deanishe.net/env"
 )
 
 // TestConfigEnv verifies that Config holds the expected values.
@@ -57,6 +60,236 @@ func TestBundleID(t *testing.T) {
 	assert.Equal(t, x, cfg.getBundleID(x), "unexpected bundle ID")
 }
 
+// TestConfig_GetDuration verifies parsing of durations.
+func TestConfig_GetDuration(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig(env.MapEnv{
+		"DURATION": "1m30s",
+		"SECONDS":  "30",
+		"INVALID":  "thirty seconds",
+		"EMPTY":    "",
+	})
+	fallback := time.Hour
+
+	tests := []struct {
+		key string
+		x   time.Duration
+	}{
+		{"DURATION", time.Second * 90},
+		{"SECONDS", time.Second * 30},
+		{"INVALID", fallback},
+		{"EMPTY", fallback},
+		{"UNSET", fallback},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.key, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.x, cfg.GetDuration(td.key, fallback), "unexpected duration")
+		})
+	}
+
+	assert.Equal(t, time.Duration(0), cfg.GetDuration("INVALID"), "unexpected default")
+}
+
+// TestConfig_GetStringSlice verifies that lists are split and cleaned.
+func TestConfig_GetStringSlice(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig(env.MapEnv{
+		"COMMAS":   "one, two,,three , ",
+		"NEWLINES": "one\n  two\n\nthree\n",
+		"EMPTY":    "",
+	})
+	fallback := []string{"fallback"}
+
+	tests := []struct {
+		key, sep string
+		x        []string
+	}{
+		{"COMMAS", ",", []string{"one", "two", "three"}},
+		{"NEWLINES", "\n", []string{"one", "two", "three"}},
+		{"EMPTY", ",", []string{}},
+		{"UNSET", ",", fallback},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.key, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.x, cfg.GetStringSlice(td.key, td.sep, fallback), "unexpected value")
+		})
+	}
+}
+
+// TestConfig_GetJSON verifies that JSON variables are unmarshalled.
+func TestConfig_GetJSON(t *testing.T) {
+	t.Parallel()
+
+	type feed struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+
+	cfg := NewConfig(env.MapEnv{
+		"FEEDS":   `[{"name": "one", "url": "https://example.com/1"}]`,
+		"INVALID": `[{"name": "one"`,
+	})
+
+	var feeds []feed
+	require.Nil(t, cfg.GetJSON("FEEDS", &feeds), "GetJSON failed")
+	assert.Equal(t, []feed{{"one", "https://example.com/1"}}, feeds, "unexpected value")
+
+	// unset variable leaves default untouched
+	defaults := []feed{{"default", "https://example.com"}}
+	require.Nil(t, cfg.GetJSON("UNSET", &defaults), "GetJSON failed for unset variable")
+	assert.Equal(t, []feed{{"default", "https://example.com"}}, defaults, "default overwritten")
+
+	err := cfg.GetJSON("INVALID", &feeds)
+	require.NotNil(t, err, "GetJSON accepted invalid JSON")
+	assert.Contains(t, err.Error(), "INVALID", "error does not name key")
+}
+
+// TestConfig_StringMap verifies namespaced variables are read.
+func TestConfig_StringMap(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig(env.MapEnv{
+		"feed_1_url":  "https://example.com/1",
+		"feed_1_name": "one",
+		"feed_10_url": "https://example.com/10",
+		"feed_2_url":  "https://example.com/2",
+	})
+	assert.Equal(t, map[string]string{
+		"url":  "https://example.com/1",
+		"name": "one",
+	}, cfg.StringMap("feed_1_"), "unexpected map")
+	assert.Equal(t, 4, len(cfg.StringMap("feed_")), "unexpected map size")
+	assert.Equal(t, map[string]string{}, cfg.StringMap("nope_"), "unexpected map")
+}
+
+// TestConfig_Alfred verifies Alfred version and theme info.
+func TestConfig_Alfred(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig(env.MapEnv{
+		EnvVarAlfredVersion: "4.5.1",
+		EnvVarAlfredBuild:   "1256",
+		EnvVarTheme:         "theme.bundled.dark",
+	})
+	assert.Equal(t, "4.5.1", cfg.AlfredVersion(), "unexpected version")
+	assert.Equal(t, 1256, cfg.AlfredBuild(), "unexpected build")
+	assert.Equal(t, "theme.bundled.dark", cfg.Theme(), "unexpected theme")
+
+	cfg = NewConfig(env.MapEnv{})
+	assert.Equal(t, "", cfg.AlfredVersion(), "unexpected version")
+	assert.Equal(t, 0, cfg.AlfredBuild(), "unexpected build")
+	assert.Equal(t, "", cfg.Theme(), "unexpected theme")
+
+	tests := []struct {
+		theme, bg string
+		x         bool
+	}{
+		{"", "", false},
+		{"theme.bundled.dark", "", true},
+		{"theme.bundled.light", "", false},
+		{"theme.custom.1234", "rgba(22,22,22,0.95)", true},
+		{"theme.custom.1234", "rgba(255, 255, 255, 1.00)", false},
+		// background colour takes precedence over name
+		{"theme.custom.darkish", "rgba(240,240,240,1.0)", false},
+		// invalid colour
+		{"theme.bundled.dark", "black", true},
+	}
+
+	for _, td := range tests {
+		cfg := NewConfig(env.MapEnv{EnvVarTheme: td.theme, EnvVarThemeBG: td.bg})
+		assert.Equal(t, td.x, cfg.IsDarkTheme(), "unexpected result for %q, %q", td.theme, td.bg)
+	}
+}
+
+// TestConfig_Dump verifies variables are dumped and secrets redacted.
+func TestConfig_Dump(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig(env.MapEnv{
+		EnvVarBundleID:  "net.deanishe.awgo",
+		"API_KEY":       "hunter2",
+		"user_password": "hunter2",
+		"EMPTY_TOKEN":   "",
+		"feed_url":      "https://example.com",
+		"author":        "Dean",
+		"PATH":          "/usr/bin:/bin",
+		"XPC_FLAGS":     "0x0",
+	})
+	x := map[string]string{
+		EnvVarBundleID:  "net.deanishe.awgo",
+		"API_KEY":       redacted,
+		"user_password": redacted,
+		"EMPTY_TOKEN":   "",
+		"feed_url":      "https://example.com",
+		"author":        "Dean",
+	}
+	assert.Equal(t, x, cfg.Dump(), "unexpected dump")
+}
+
+// TestSecretVarPattern verifies which variable names are secret.
+func TestSecretVarPattern(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		x    bool
+	}{
+		{"API_KEY", true},
+		{"apikey", true},
+		{"api-key", true},
+		{"github_token", true},
+		{"CLIENT_SECRET", true},
+		{"user_password", true},
+		{"DB_PASS", true},
+		{"passwd", true},
+		{"smtp.pwd", true},
+		{"AUTH_HEADER", true},
+		{"auth_token", true},
+		{"author", false},
+		{"AUTHOR_NAME", false},
+		{"passenger", false},
+		{"tokenizer", false},
+		{"feed_url", false},
+	}
+
+	for _, td := range tests {
+		assert.Equal(t, td.x, SecretVarPattern.MatchString(td.name), "unexpected result for %q", td.name)
+	}
+}
+
+// TestConfig_Diff verifies comparison to a snapshot.
+func TestConfig_Diff(t *testing.T) {
+	t.Parallel()
+
+	snapshot := map[string]string{
+		"same":    "1",
+		"changed": "old",
+		"removed": "gone",
+		"TOKEN":   redacted,
+	}
+	cfg := NewConfig(env.MapEnv{
+		"same":    "1",
+		"changed": "new",
+		"added":   "here",
+		"TOKEN":   "different secret",
+	})
+	x := []EnvChange{
+		{Key: "added", New: "here", Added: true},
+		{Key: "changed", Old: "old", New: "new"},
+		{Key: "removed", Old: "gone", Removed: true},
+	}
+	assert.Equal(t, x, cfg.Diff(snapshot), "unexpected diff")
+	assert.Nil(t, cfg.Diff(cfg.Dump()), "unexpected diff with own dump")
+}
+
 // Basic usage of Config.Get. Returns an empty string if variable is unset.
 func ExampleConfig_Get() {
 	// Set some test variables
@@ -235,3 +468,61 @@ func unsetEnv(keys ...string) {
 		panicOnErr(os.Unsetenv(key))
 	}
 }
+
+// Export applies changes to Config.
+func TestConfig_Export(t *testing.T) {
+	orig := runJS
+	defer func() { runJS = orig }()
+	mj := &mockJSRunner{}
+	runJS = mj.Run
+
+	cfg := NewConfig(env.MapEnv{
+		EnvVarBundleID: "net.deanishe.awgo",
+		"COUNTER":      "1",
+		"LAST":         "old",
+	})
+	assert.Nil(t, cfg.Export(), "Export with no changes failed")
+
+	cfg.Set("COUNTER", "2", false).Set("NEW", "new", false).Unset("LAST")
+	// changes to other workflows are ignored
+	cfg.Set("OTHER", "other", false, "net.deanishe.other")
+	assert.Equal(t, 1, cfg.GetInt("COUNTER"), "Config changed before Export")
+
+	require.Nil(t, cfg.Export(), "Export failed")
+	assert.Contains(t, mj.script, `setConfiguration("COUNTER"`, "Set not run")
+	assert.Equal(t, 2, cfg.GetInt("COUNTER"), "unexpected COUNTER")
+	assert.Equal(t, "new", cfg.Get("NEW"), "unexpected NEW")
+	_, ok := cfg.Lookup("LAST")
+	assert.False(t, ok, "LAST not unset")
+	_, ok = cfg.Lookup("OTHER")
+	assert.False(t, ok, "other workflow's variable set")
+	assert.Equal(t, []string{"COUNTER", "NEW", EnvVarBundleID}, cfg.keys(), "unexpected keys")
+
+	// changes aren't applied if call to Alfred fails
+	runJS = func(string) error { return errors.New("failed") }
+	cfg.Set("COUNTER", "3", false)
+	assert.NotNil(t, cfg.Export(), "Export error not returned")
+	assert.Equal(t, 2, cfg.GetInt("COUNTER"), "failed change applied")
+}
+
+// Repeated overrides are merged, not nested.
+func TestConfig_override(t *testing.T) {
+	t.Parallel()
+
+	base := env.MapEnv{"A": "a", "B": "b"}
+	cfg := NewConfig(base)
+	cfg.override(map[string]string{"A": "1", "C": "c"}, map[string]bool{"B": true})
+	cfg.override(map[string]string{"B": "2"}, map[string]bool{"C": true})
+
+	e, ok := cfg.Env.(overrideEnv)
+	require.True(t, ok, "Env not overridden")
+	_, nested := e.Env.(overrideEnv)
+	assert.False(t, nested, "overrides nested")
+
+	assert.Equal(t, "1", cfg.Get("A"), "unexpected A")
+	assert.Equal(t, "2", cfg.Get("B"), "unexpected B")
+	_, ok = cfg.Lookup("C")
+	assert.False(t, ok, "C not unset")
+	assert.Equal(t, []string{"A", "B"}, cfg.keys(), "unexpected keys")
+	assert.Equal(t, env.MapEnv{"A": "a", "B": "b"}, base, "wrapped Env changed")
+}
@@ -202,7 +202,9 @@ to Alfred, so their output is only visible in the log. It is rotated when
 it exceeds 1 MiB in size. One previous log is kept.
 
 AwGo detects when Alfred's debugger is open (Workflow.Debug() returns true)
-and in this case prepends filename:linenumber: to log messages.
+and in this case prepends filename:linenumber: to log messages. It also
+logs extra diagnostic messages, e.g. which magic action was run and how
+many items were sent to Alfred.
 
 
 Workflow settings
@@ -270,7 +272,9 @@ These all share (almost) the same API. The difference is in when the data go
 away.
 
 Data saved with Session are deleted after the user closes Alfred or starts
-using a different workflow. The Cache directory is in a system cache
+using a different workflow. Workflow.IsNewSession() reports whether this is
+the first run of a new session, so you can also reset any transient state
+stored elsewhere. The Cache directory is in a system cache
 directory, so may be deleted by the system or "system maintenance" tools.
 
 The Data directory lives with Alfred's application data and would not
@@ -289,6 +293,14 @@ running checks for updates and other jobs that hit the network or take a
 significant amount of time to complete, allowing you to keep your Script
 Filters extremely responsive.
 
+For Script Filters that query an API as the user types, use
+RunInBackgroundDebounced() to only start the job once the query has
+stopped changing. It sets Feedback.Rerun() while waiting, so Alfred
+re-runs the Script Filter until the job has been started.
+
+A background job can report its progress via Workflow.Progress(), and
+the Script Filter can display it with Workflow.ShowProgress().
+
 See _examples/update and _examples/workflows for demonstrations of this API.
 
 
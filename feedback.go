@@ -5,7 +5,9 @@ package aw
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"sort"
@@ -44,15 +46,21 @@ type Item struct {
 	uid          *string
 	autocomplete *string
 	arg          []string
+	argFunc      func() (string, error) // Generates arg when Item is sent
 	valid        bool
 	file         bool
+	skipCheck    bool // Don't let Alfred check that file exists
+	skip         bool // Don't let Alfred learn from selection
+	pinned       bool // Sort to top with Feedback.SortPinned()
 	copytext     *string
 	largetype    *string
 	ql           *string
+	action       *itemAction
 	vars         map[string]string
 	mods         map[ModKey]*Modifier
 	icon         *Icon
-	noUID        bool // Suppress UID in JSON
+	noUID        bool         // Suppress UID in JSON
+	matchOrder   []MatchField // Overrides Feedback.MatchOrder
 }
 
 // Title sets the title of the item in Alfred's results.
@@ -67,6 +75,26 @@ func (it *Item) Subtitle(s string) *Item {
 	return it
 }
 
+// Subtitles sets the Item's subtitle and the subtitles of its
+// cmd, alt, ctrl and shift Modifiers in one call. Empty strings are
+// ignored, so you can set only the subtitles you need:
+//
+//	it.Subtitles("Open URL", "Copy URL", "", "", "Preview URL")
+func (it *Item) Subtitles(base, cmd, alt, ctrl, shift string) *Item {
+	if base != "" {
+		it.Subtitle(base)
+	}
+	for _, m := range []struct {
+		key ModKey
+		s   string
+	}{{ModCmd, cmd}, {ModAlt, alt}, {ModCtrl, ctrl}, {ModShift, shift}} {
+		if m.s != "" {
+			it.modifier(m.key).Subtitle(m.s)
+		}
+	}
+	return it
+}
+
 // Match sets Item's match field for filtering.
 // If present, this field is preferred over the item's title for fuzzy sorting
 // via Feedback, and by Alfred's "Alfred filters results" feature.
@@ -75,13 +103,51 @@ func (it *Item) Match(s string) *Item {
 	return it
 }
 
+// MatchOrder sets the fields fuzzy filtering matches the Item against,
+// overriding Feedback.MatchOrder for this Item.
+func (it *Item) MatchOrder(fields ...MatchField) *Item {
+	it.matchOrder = fields
+	return it
+}
+
 // Arg sets Item's arg, the value(s) passed as {query} to the next workflow action.
 // Multiple values are allowed in Alfred 4.1 and later.
 func (it *Item) Arg(s ...string) *Item {
 	it.arg = s
+	it.argFunc = nil
 	return it
 }
 
+// Args is a synonym for Arg.
+func (it *Item) Args(s ...string) *Item { return it.Arg(s...) }
+
+// ArgFunc sets a function that generates Item's arg. It is called once,
+// when the Item's Feedback is sent (or encoded), so an expensive arg is
+// only generated for Items that are sent to Alfred, i.e. not for Items
+// removed by Filter() or MaxResults. If fn returns an error, the error is
+// logged and the Item is sent without an arg and marked invalid.
+func (it *Item) ArgFunc(fn func() (string, error)) *Item {
+	it.arg = nil
+	it.argFunc = fn
+	return it
+}
+
+// resolveArg calls argFunc (if set) and sets arg to the result.
+func (it *Item) resolveArg() {
+	if it.argFunc == nil {
+		return
+	}
+	fn := it.argFunc
+	it.argFunc = nil
+	s, err := fn()
+	if err != nil {
+		log.Printf("[ERROR] generate arg for item %q: %v", it.title, err)
+		it.valid = false
+		return
+	}
+	it.arg = []string{s}
+}
+
 // UID sets Item's unique ID, which is used by Alfred to remember your choices.
 // Use a blank string to force results to appear in the order you add them.
 //
@@ -101,6 +167,14 @@ func (it *Item) Autocomplete(s string) *Item {
 	return it
 }
 
+// AutocompleteOnly sets Item's autocomplete and makes it invalid, so
+// both TAB and RETURN expand Alfred's query to s, and the Item can't
+// be actioned. A valid Item with autocomplete is actioned on RETURN
+// and only autocompletes on TAB.
+func (it *Item) AutocompleteOnly(s string) *Item {
+	return it.Autocomplete(s).Valid(false)
+}
+
 // Valid tells Alfred whether the result is "actionable", i.e. ENTER will
 // pass Arg to subsequent action.
 func (it *Item) Valid(b bool) *Item {
@@ -108,10 +182,90 @@ func (it *Item) Valid(b bool) *Item {
 	return it
 }
 
+// Invalid makes the Item non-actionable and sets its subtitle to reason,
+// which should tell the user why the Item can't be actioned.
+func (it *Item) Invalid(reason string) *Item {
+	return it.Valid(false).Subtitle(reason)
+}
+
+// IsValid returns true if the Item is actionable.
+func (it *Item) IsValid() bool { return it.valid }
+
+// SkipKnowledge tells Alfred not to learn from the user selecting
+// this Item, so the Item's position isn't affected by Alfred's knowledge.
+// Only Alfred 3.6+ supports it; older versions ignore it.
+func (it *Item) SkipKnowledge(b bool) *Item {
+	it.skip = b
+	return it
+}
+
+// Action sets the value(s) passed to Alfred's Universal Actions when the
+// user actions the Item with the Universal Action hotkey. Alfred determines
+// whether the values are text, URLs or files.
+// Use ActionText, ActionURL and ActionFile to specify the type.
+// Alfred 4.5+ only.
+func (it *Item) Action(value ...string) *Item {
+	it.getAction().auto = value
+	return it
+}
+
+// ActionText sets text value(s) passed to Universal Actions.
+// Alfred 4.5+ only.
+func (it *Item) ActionText(value ...string) *Item {
+	it.getAction().text = value
+	return it
+}
+
+// ActionURL sets URL value(s) passed to Universal Actions.
+// Alfred 4.5+ only.
+func (it *Item) ActionURL(value ...string) *Item {
+	it.getAction().url = value
+	return it
+}
+
+// ActionFile sets file path value(s) passed to Universal Actions.
+// Alfred 4.5+ only.
+func (it *Item) ActionFile(value ...string) *Item {
+	it.getAction().file = value
+	return it
+}
+
+// getAction returns Item's itemAction, creating it if necessary.
+func (it *Item) getAction() *itemAction {
+	if it.action == nil {
+		it.action = &itemAction{}
+	}
+	return it.action
+}
+
+// Pin marks the Item as pinned, so Feedback.SortPinned() moves it to the
+// top of the results. It also sets SkipKnowledge, so Alfred doesn't
+// re-rank the Item based on the user's selections.
+func (it *Item) Pin() *Item {
+	it.pinned = true
+	return it.SkipKnowledge(true)
+}
+
+// IsPinned returns true if the Item is pinned.
+func (it *Item) IsPinned() bool { return it.pinned }
+
 // IsFile tells Alfred that this Item is a file, i.e. Arg is a path
 // and Alfred's File Actions should be made available.
+//
+// Alfred needs a path to act on, so a warning is logged if an Item
+// marked as a file has no Arg when it is sent to Alfred.
 func (it *Item) IsFile(b bool) *Item {
 	it.file = b
+	it.skipCheck = false
+	return it
+}
+
+// IsFileSkipCheck is like IsFile, but Alfred doesn't check that the file
+// exists ("file:skipcheck" type). This is faster, e.g. for paths on slow
+// network volumes, and works for files that haven't been created yet.
+func (it *Item) IsFileSkipCheck(b bool) *Item {
+	it.file = b
+	it.skipCheck = b
 	return it
 }
 
@@ -135,6 +289,9 @@ func (it *Item) Quicklook(s string) *Item {
 	return it
 }
 
+// QuicklookURL is a synonym for Quicklook.
+func (it *Item) QuicklookURL(s string) *Item { return it.Quicklook(s) }
+
 // Icon sets the icon for the Item.
 // Can point to an image file, a filepath of a file whose icon should be used,
 // or a UTI.
@@ -154,11 +311,29 @@ func (it *Item) Var(k, v string) *Item {
 	return it
 }
 
+// SetVars sets all the variables in vars on the Item, overwriting any
+// existing variables with the same names. It is the bulk version of Var(),
+// e.g. to pass on a group of settings read with Config.StringMap().
+func (it *Item) SetVars(vars map[string]string) *Item {
+	for k, v := range vars {
+		it.Var(k, v)
+	}
+	return it
+}
+
 // NewModifier returns an initialised Modifier bound to this Item.
 // It also populates the Modifier with any workflow variables set in the Item.
 //
 // You must specify at least one modifier key. Alfred 3 only supports
-// a single modifier, but Alfred 4+ allow them to be arbitrarily combined.
+// a single modifier, but Alfred 4+ allow cmd, alt, ctrl and shift to be
+// combined, e.g. NewModifier(ModCmd, ModAlt) creates a "cmd+alt" Modifier.
+// fn can't be combined and is ignored if other keys are given.
+//
+// The keys of a compound Modifier are always in Alfred's order (cmd, alt,
+// ctrl, shift), regardless of the order they're passed in. Earlier versions
+// sorted them alphabetically, so a Modifier that used to have Key "alt+cmd"
+// now has Key "cmd+alt". Use Item.Mod() to look up a Modifier instead of
+// comparing key strings.
 // Any invalid modifier keys are ignored. If you specify an unusable set of
 // modifiers (i.e. they evaluate to ""), although a Modifier is returned,
 // it is not retained by Item and will not be sent to Alfred. An error message
@@ -189,22 +364,103 @@ func (it *Item) SetModifier(m *Modifier) {
 }
 
 // Cmd returns an initialised Modifier bound to this Item and the CMD (⌘) key.
-func (it *Item) Cmd() *Modifier { return it.NewModifier(ModCmd) }
+func (it *Item) Cmd() *Modifier { return it.modifier(ModCmd) }
 
 // Alt returns an initialised Modifier bound to this Item and the ALT/OPT (⌥) key.
-func (it *Item) Alt() *Modifier { return it.NewModifier(ModAlt) }
+func (it *Item) Alt() *Modifier { return it.modifier(ModAlt) }
 
 // Opt is a synonym for Alt().
 func (it *Item) Opt() *Modifier { return it.Alt() }
 
 // Ctrl returns an initialised Modifier bound to this Item and the CTRL (^) key.
-func (it *Item) Ctrl() *Modifier { return it.NewModifier(ModCtrl) }
+func (it *Item) Ctrl() *Modifier { return it.modifier(ModCtrl) }
 
 // Shift returns an initialised Modifier bound to this Item and the SHIFT (⇧) key.
-func (it *Item) Shift() *Modifier { return it.NewModifier(ModShift) }
+func (it *Item) Shift() *Modifier { return it.modifier(ModShift) }
 
 // Fn returns an initialised Modifier bound to this Item and the fn key.
-func (it *Item) Fn() *Modifier { return it.NewModifier(ModFn) }
+func (it *Item) Fn() *Modifier { return it.modifier(ModFn) }
+
+// Mod returns the Modifier set for the given modifier key(s), e.g.
+// Mod(ModCmd) or Mod(ModCmd, ModShift), and whether one is set.
+// Unlike Cmd(), Alt() etc., it doesn't create a new Modifier,
+// so it can be used to inspect an Item, e.g. in tests.
+func (it *Item) Mod(key ...ModKey) (*Modifier, bool) {
+	k := newModifier(key...).Key
+	if k == "" {
+		return nil, false
+	}
+	m, ok := it.mods[k]
+	return m, ok
+}
+
+// modifier returns Item's existing Modifier for key or creates a new one.
+// It backs the Cmd(), Alt() etc. shortcuts, so calling one repeatedly
+// configures the same Modifier.
+func (it *Item) modifier(key ModKey) *Modifier {
+	if m, ok := it.mods[key]; ok {
+		return m
+	}
+	return it.NewModifier(key)
+}
+
+// Equal returns true if other has the same values as Item, i.e.
+// both produce the same JSON for Alfred. Lazy args (see ArgFunc) aren't
+// generated, so an Item with one is only equal to itself.
+func (it *Item) Equal(other *Item) bool {
+	if it == nil || other == nil || it == other {
+		return it == other
+	}
+	if it.argFunc != nil || other.argFunc != nil {
+		return false
+	}
+	if it.title != other.title ||
+		it.valid != other.valid ||
+		it.skip != other.skip ||
+		it.file != other.file ||
+		it.file && it.skipCheck != other.skipCheck {
+		return false
+	}
+	for _, p := range [][2]*string{
+		{it.subtitle, other.subtitle},
+		{it.match, other.match},
+		{it.uid, other.uid},
+		{it.autocomplete, other.autocomplete},
+		{it.copytext, other.copytext},
+		{it.largetype, other.largetype},
+	} {
+		if !equalStringPtr(p[0], p[1]) {
+			return false
+		}
+	}
+	var qlA, qlB string
+	if it.ql != nil {
+		qlA = *it.ql
+	}
+	if other.ql != nil {
+		qlB = *other.ql
+	}
+	if qlA != qlB {
+		return false
+	}
+	if !equalStrings(it.arg, other.arg) ||
+		!equalVars(it.vars, other.vars) ||
+		!equalIcon(it.icon, other.icon) ||
+		!it.action.equal(other.action) {
+		return false
+	}
+
+	a, b := it.modsJSON(), other.modsJSON()
+	if len(a) != len(b) {
+		return false
+	}
+	for k, m := range a {
+		if !m.equal(b[k]) {
+			return false
+		}
+	}
+	return true
+}
 
 // Vars returns the Item's workflow variables.
 func (it *Item) Vars() map[string]string {
@@ -222,6 +478,12 @@ func (it *Item) MarshalJSON() ([]byte, error) {
 
 	if it.file {
 		typ = "file"
+		if it.skipCheck {
+			typ = "file:skipcheck"
+		}
+		if len(it.arg) == 0 || it.arg[0] == "" {
+			log.Printf("[warning] item %q is a file but has no arg (path)", it.title)
+		}
 	}
 
 	if it.ql != nil {
@@ -241,10 +503,12 @@ func (it *Item) MarshalJSON() ([]byte, error) {
 		Arg       interface{}          `json:"arg,omitempty"`
 		UID       *string              `json:"uid,omitempty"`
 		Valid     bool                 `json:"valid"`
+		Skip      bool                 `json:"skipknowledge,omitempty"`
 		Type      string               `json:"type,omitempty"`
 		Text      *itemText            `json:"text,omitempty"`
 		Icon      *Icon                `json:"icon,omitempty"`
 		Quicklook string               `json:"quicklookurl,omitempty"`
+		Action    *itemAction          `json:"action,omitempty"`
 		Variables map[string]string    `json:"variables,omitempty"`
 		Mods      map[ModKey]*Modifier `json:"mods,omitempty"`
 	}{
@@ -254,22 +518,109 @@ func (it *Item) MarshalJSON() ([]byte, error) {
 		Auto:      it.autocomplete,
 		UID:       it.uid,
 		Valid:     it.valid,
+		Skip:      it.skip,
 		Type:      typ,
 		Text:      text,
 		Icon:      it.icon,
 		Quicklook: ql,
 		Variables: it.vars,
-		Mods:      it.mods,
+		Action:    it.action,
+		Mods:      it.modsJSON(),
 	}
-	// serialise single arg as string
-	if len(it.arg) == 1 {
-		v.Arg = it.arg[0]
-	} else if len(it.arg) > 1 {
-		v.Arg = it.arg
+	if v.Action != nil && v.Action.isEmpty() {
+		v.Action = nil
 	}
+	v.Arg = stringOrSlice(it.arg)
 	return json.Marshal(v)
 }
 
+// modsJSON returns the Item's Modifiers for serialisation. Modifiers set
+// to inherit the Item's subtitle are replaced by copies with the subtitle.
+func (it *Item) modsJSON() map[ModKey]*Modifier {
+	if it.subtitle == nil {
+		return it.mods
+	}
+	var mods map[ModKey]*Modifier
+	for k, m := range it.mods {
+		if !m.inheritSubtitle || m.subtitle != nil {
+			continue
+		}
+		if mods == nil {
+			mods = make(map[ModKey]*Modifier, len(it.mods))
+			for k, m := range it.mods {
+				mods[k] = m
+			}
+		}
+		m2 := *m
+		m2.subtitle = it.subtitle
+		mods[k] = &m2
+	}
+	if mods == nil {
+		return it.mods
+	}
+	return mods
+}
+
+// stringOrSlice returns nil for an empty slice, a string for a slice with
+// one element, and the slice otherwise.
+func stringOrSlice(l []string) interface{} {
+	switch len(l) {
+	case 0:
+		return nil
+	case 1:
+		return l[0]
+	default:
+		return l
+	}
+}
+
+// itemAction is the data an Item passes to Alfred's Universal Actions.
+type itemAction struct {
+	auto []string // Alfred determines type
+	text []string
+	url  []string
+	file []string
+}
+
+// isEmpty returns true if no values are set.
+func (a *itemAction) isEmpty() bool {
+	return len(a.auto) == 0 && len(a.text) == 0 && len(a.url) == 0 && len(a.file) == 0
+}
+
+// MarshalJSON serializes itemAction to a string or array if only untyped
+// values are set, otherwise to an object keyed by type.
+func (a *itemAction) MarshalJSON() ([]byte, error) {
+	if len(a.text) == 0 && len(a.url) == 0 && len(a.file) == 0 {
+		return json.Marshal(stringOrSlice(a.auto))
+	}
+	return json.Marshal(struct {
+		Auto interface{} `json:"auto,omitempty"`
+		Text interface{} `json:"text,omitempty"`
+		URL  interface{} `json:"url,omitempty"`
+		File interface{} `json:"file,omitempty"`
+	}{
+		Auto: stringOrSlice(a.auto),
+		Text: stringOrSlice(a.text),
+		URL:  stringOrSlice(a.url),
+		File: stringOrSlice(a.file),
+	})
+}
+
+// equal returns true if both itemActions contain the same values.
+// A nil itemAction is equal to an empty one.
+func (a *itemAction) equal(other *itemAction) bool {
+	if a == nil {
+		a = &itemAction{}
+	}
+	if other == nil {
+		other = &itemAction{}
+	}
+	return equalStrings(a.auto, other.auto) &&
+		equalStrings(a.text, other.text) &&
+		equalStrings(a.url, other.url) &&
+		equalStrings(a.file, other.file)
+}
+
 // itemText encapsulates the copytext and largetext values for a result Item.
 type itemText struct {
 	// Copied to the clipboard on CMD+C
@@ -287,18 +638,29 @@ type itemText struct {
 // after creating the Modifier are not inherited.
 type Modifier struct {
 	// The modifier key, e.g. "cmd", "alt".
-	// With Alfred 4+, modifiers can be combined, e.g. "cmd+alt", "ctrl+shift+cmd"
+	// With Alfred 4+, modifiers can be combined, e.g. "cmd+alt", "cmd+ctrl+shift"
 	Key      ModKey
 	arg      []string
 	subtitle *string
 	valid    bool
+	validSet bool // Valid() was called, so send valid even if false
 	icon     *Icon
 	vars     map[string]string
+	// Use Item's subtitle if subtitle isn't set
+	inheritSubtitle bool
 }
 
+// modOrder is the canonical order of modifiers in a compound key.
+// fn can't be combined with other modifiers, so it isn't included.
+var modOrder = []string{"cmd", "alt", "ctrl", "shift"}
+
 // newModifier creates a Modifier, validating key.
+// Duplicate keys are ignored, and the keys of a compound modifier
+// are joined in canonical order, e.g. "cmd+alt+shift". fn is only
+// valid on its own and is ignored if combined with other keys.
 func newModifier(key ...ModKey) *Modifier {
-	l := []string{}
+	var fn bool
+	seen := map[string]bool{}
 	for _, k := range key {
 		s := strings.TrimSpace(strings.ToLower(string(k)))
 		if s == "opt" {
@@ -307,13 +669,29 @@ func newModifier(key ...ModKey) *Modifier {
 		if s == "" {
 			continue
 		}
-		if s != "alt" && s != "cmd" && s != "ctrl" && s != "fn" && s != "shift" {
+		if s == "fn" {
+			fn = true
+			continue
+		}
+		if s != "alt" && s != "cmd" && s != "ctrl" && s != "shift" {
 			log.Printf("[warning] ignored invalid modifier %q", k)
 			continue
 		}
-		l = append(l, s)
+		seen[s] = true
+	}
+	l := []string{}
+	for _, s := range modOrder {
+		if seen[s] {
+			l = append(l, s)
+		}
+	}
+	if fn {
+		if len(l) > 0 {
+			log.Printf("[warning] ignored modifier \"fn\": it can't be combined with other modifiers")
+		} else {
+			l = append(l, "fn")
+		}
 	}
-	sort.Strings(l)
 	s := strings.Join(l, "+")
 	return &Modifier{Key: ModKey(s), vars: map[string]string{}}
 }
@@ -330,9 +708,21 @@ func (m *Modifier) Subtitle(s string) *Modifier {
 	return m
 }
 
-// Valid sets the valid status for the Modifier.
+// InheritSubtitle tells the Modifier to show its Item's subtitle if it
+// has no subtitle of its own. Alfred does this anyway, but setting the
+// subtitle explicitly makes it clear it also applies to the modifier,
+// e.g. for invalid Modifiers.
+func (m *Modifier) InheritSubtitle() *Modifier {
+	m.inheritSubtitle = true
+	return m
+}
+
+// Valid sets the valid status for the Modifier. If it isn't set, Alfred
+// uses the Item's valid status, so a Modifier can make an otherwise
+// invalid Item actionable (or vice versa).
 func (m *Modifier) Valid(v bool) *Modifier {
 	m.valid = v
+	m.validSet = true
 	return m
 }
 
@@ -359,15 +749,17 @@ func (m *Modifier) MarshalJSON() ([]byte, error) {
 	v := struct {
 		Arg       interface{}       `json:"arg,omitempty"`
 		Subtitle  *string           `json:"subtitle,omitempty"`
-		Valid     bool              `json:"valid,omitempty"`
+		Valid     *bool             `json:"valid,omitempty"`
 		Icon      *Icon             `json:"icon,omitempty"`
 		Variables map[string]string `json:"variables,omitempty"`
 	}{
 		Subtitle:  m.subtitle,
-		Valid:     m.valid,
 		Icon:      m.icon,
 		Variables: m.vars,
 	}
+	if m.validSet || m.valid {
+		v.Valid = &m.valid
+	}
 
 	// serialise single arg as string
 	if len(m.arg) == 1 {
@@ -379,6 +771,68 @@ func (m *Modifier) MarshalJSON() ([]byte, error) {
 	return json.Marshal(v)
 }
 
+// equal returns true if both Modifiers produce the same JSON.
+func (m *Modifier) equal(other *Modifier) bool {
+	if m == nil || other == nil {
+		return m == other
+	}
+	return m.valid == other.valid &&
+		(m.validSet || m.valid) == (other.validSet || other.valid) &&
+		equalStrings(m.arg, other.arg) &&
+		equalStringPtr(m.subtitle, other.subtitle) &&
+		equalIcon(m.icon, other.icon) &&
+		equalVars(m.vars, other.vars)
+}
+
+// equalStringPtr returns true if a and b are both nil or point to the same value.
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// equalStrings returns true if a and b contain the same strings.
+// A nil slice is equal to an empty one.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalVars returns true if a and b contain the same variables.
+func equalVars(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if v2, ok := b[k]; !ok || v != v2 {
+			return false
+		}
+	}
+	return true
+}
+
+// equalIcon returns true if a and b are both nil or the same icon.
+func equalIcon(a, b *Icon) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Range of rerun values accepted by Alfred.
+const (
+	minRerun = 0.1
+	maxRerun = 5.0
+)
+
 // Feedback represents the results for an Alfred Script Filter.
 //
 // Normally, you won't use this struct directly, but via the Workflow methods
@@ -386,11 +840,17 @@ func (m *Modifier) MarshalJSON() ([]byte, error) {
 // functions for Feedback, Item and Modifier structs so they are properly
 // initialised and bound to their parent.
 type Feedback struct {
-	Items  []*Item           // The results to be sent to Alfred.
-	NoUIDs bool              // If true, suppress Item UIDs.
-	rerun  float64           // Tell Alfred to re-run Script Filter.
-	sent   bool              // Set to true when feedback has been sent.
-	vars   map[string]string // Top-level feedback variables.
+	Items  []*Item // The results to be sent to Alfred.
+	NoUIDs bool    // If true, suppress Item UIDs.
+	// If non-zero, Filter() also removes matching Items whose fuzzy
+	// score is lower than MinScore.
+	MinScore float64
+	// Fields Sort() and Filter() match Items against. The first non-empty
+	// field is used. Default: FieldMatch, FieldTitle
+	MatchOrder []MatchField
+	rerun      float64           // Tell Alfred to re-run Script Filter.
+	sent       bool              // Set to true when feedback has been sent.
+	vars       map[string]string // Top-level feedback variables.
 }
 
 // NewFeedback creates a new, initialised Feedback struct.
@@ -408,7 +868,18 @@ func (fb *Feedback) Var(k, v string) *Feedback {
 }
 
 // Rerun tells Alfred to re-run the Script Filter after `secs` seconds.
+//
+// Alfred accepts values between 0.1 and 5.0, so secs is clamped to that
+// range. If secs is 0 (or negative), rerun is turned off.
 func (fb *Feedback) Rerun(secs float64) *Feedback {
+	switch {
+	case secs <= 0:
+		secs = 0
+	case secs < minRerun:
+		secs = minRerun
+	case secs > maxRerun:
+		secs = maxRerun
+	}
 	fb.rerun = secs
 	return fb
 }
@@ -418,11 +889,36 @@ func (fb *Feedback) Vars() map[string]string {
 	return fb.vars
 }
 
-// Clear removes any items.
+// Clear removes any items and resets top-level fields (rerun and
+// variables), so Feedback can be reused.
 func (fb *Feedback) Clear() {
-	if len(fb.Items) > 0 {
-		fb.Items = []*Item{}
+	fb.Items = []*Item{}
+	fb.rerun = 0
+	fb.vars = map[string]string{}
+}
+
+// Append adds other's Items to the end of Feedback, e.g. to combine the
+// results of several independent components. It also copies other's
+// top-level variables, but variables already set on Feedback take
+// precedence. Other settings, such as rerun, are not copied.
+func (fb *Feedback) Append(other *Feedback) *Feedback {
+	if other == nil {
+		return fb
 	}
+	fb.Items = append(fb.Items, other.Items...)
+	for k, v := range other.vars {
+		if _, ok := fb.vars[k]; !ok {
+			fb.Var(k, v)
+		}
+	}
+	return fb
+}
+
+// Reset clears Feedback (see Clear()) and marks it as unsent, so it
+// can be sent again.
+func (fb *Feedback) Reset() {
+	fb.Clear()
+	fb.sent = false
 }
 
 // IsEmpty returns true if Feedback contains no items.
@@ -451,32 +947,146 @@ func (fb *Feedback) NewItem(title string) *Item {
 // MarshalJSON serializes Feedback to Alfred's JSON format.
 // You shouldn't need to call this: use Send() instead.
 func (fb *Feedback) MarshalJSON() ([]byte, error) {
+	// Alfred expects an array, even if there are no items
+	items := fb.Items
+	if items == nil {
+		items = []*Item{}
+	}
 	return json.Marshal(&struct {
 		Variables map[string]string `json:"variables,omitempty"`
 		Rerun     float64           `json:"rerun,omitempty"`
 		Items     []*Item           `json:"items"`
 	}{
-		Items:     fb.Items,
+		Items:     items,
 		Rerun:     fb.rerun,
 		Variables: fb.vars,
 	})
 }
 
+// validate checks Feedback for mistakes that cause Alfred to reject it or
+// to behave unexpectedly, and returns a description of each problem.
+// Items are numbered from 1, as in Alfred's results.
+func (fb *Feedback) validate() []string {
+	var (
+		problems []string
+		uids     = map[string]int{}
+	)
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	for k := range fb.vars {
+		if k == "" {
+			add("variable has empty name")
+		}
+	}
+
+	for i, it := range fb.Items {
+		n := i + 1
+		hasArg := len(it.arg) > 0 && it.arg[0] != "" || it.argFunc != nil
+		if it.title == "" {
+			add("item %d: title is empty", n)
+		}
+		if it.valid && !hasArg {
+			add("item %d (%q): arg is empty but valid=true", n, it.title)
+		}
+		if it.file && !hasArg {
+			add("item %d (%q): item is a file but arg (path) is empty", n, it.title)
+		}
+		if it.uid != nil && !it.noUID {
+			if j, ok := uids[*it.uid]; ok {
+				add("item %d (%q): UID %q is also used by item %d", n, it.title, *it.uid, j)
+			} else {
+				uids[*it.uid] = n
+			}
+		}
+		if it.icon != nil {
+			if it.icon.Value == "" {
+				add("item %d (%q): icon has no value", n, it.title)
+			}
+			switch it.icon.Type {
+			case IconTypeImage, IconTypeFileIcon, IconTypeFileType:
+			default:
+				add("item %d (%q): invalid icon type %q", n, it.title, it.icon.Type)
+			}
+		}
+		for k := range it.vars {
+			if k == "" {
+				add("item %d (%q): variable has empty name", n, it.title)
+			}
+		}
+		for k, m := range it.mods {
+			if !isValidModKey(k) {
+				add("item %d (%q): invalid modifier key %q", n, it.title, k)
+			}
+			if m.valid && !hasArg && len(m.arg) == 0 {
+				add("item %d (%q): arg of modifier %q is empty but valid=true", n, it.title, k)
+			}
+		}
+	}
+	return problems
+}
+
+// isValidModKey returns true if k is a modifier key or a combination of
+// modifier keys, e.g. "cmd" or "cmd+alt". fn is only valid on its own.
+func isValidModKey(k ModKey) bool {
+	if k == ModFn {
+		return true
+	}
+	for _, s := range strings.Split(string(k), "+") {
+		var ok bool
+		for _, m := range modOrder {
+			if s == m {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveArgs generates the args of Items that have an ArgFunc.
+func (fb *Feedback) resolveArgs() {
+	for _, it := range fb.Items {
+		it.resolveArg()
+	}
+}
+
+// ErrFeedbackSent is returned by Feedback.Send() if feedback has already
+// been sent. Alfred can't parse more than one set of results, so call
+// Feedback.Reset() if you really need to send feedback again (e.g. in tests).
+var ErrFeedbackSent = errors.New("feedback already sent")
+
+// Encode writes Feedback as JSON to w. Unlike Send, it doesn't mark
+// Feedback as sent, so it may be called repeatedly, e.g. to capture
+// the generated JSON in tests. Lazy args (see Item.ArgFunc) are generated
+// before the Items are encoded.
+func (fb *Feedback) Encode(w io.Writer) error {
+	fb.resolveArgs()
+	output, err := json.MarshalIndent(fb, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error generating JSON : %w", err)
+	}
+	if _, err := w.Write(output); err != nil {
+		return fmt.Errorf("write feedback: %w", err)
+	}
+	return nil
+}
+
 // Send generates JSON from this struct and sends it to Alfred
-// (by writing the JSON to STDOUT).
+// (by writing the JSON to STDOUT). It returns ErrFeedbackSent if
+// called more than once.
 //
 // You shouldn't need to call this directly: use SendFeedback() instead.
 func (fb *Feedback) Send() error {
 	if fb.sent {
-		log.Printf("Feedback already sent. Ignoring.")
-		return nil
+		return ErrFeedbackSent
 	}
-	output, err := json.MarshalIndent(fb, "", "  ")
-	if err != nil {
-		return fmt.Errorf("Error generating JSON : %w", err)
+	if err := fb.Encode(os.Stdout); err != nil {
+		return err
 	}
-
-	os.Stdout.Write(output)
 	fb.sent = true
 	log.Printf("Sent %d result(s) to Alfred", len(fb.Items))
 	return nil
@@ -489,7 +1099,46 @@ func (fb *Feedback) Sort(query string, opts ...fuzzy.Option) []*fuzzy.Result {
 	return s.Sort(query)
 }
 
+// SortFunc sorts Items with the less function, e.g. to order them by
+// recency or usage instead of fuzzy score. The sort is stable, so Items
+// that are equal according to less retain their original order.
+func (fb *Feedback) SortFunc(less func(a, b *Item) bool) {
+	sort.SliceStable(fb.Items, func(i, j int) bool {
+		return less(fb.Items[i], fb.Items[j])
+	})
+}
+
+// Dedup removes Items with the same key as an earlier Item, e.g. to merge
+// cached and live results. key returns the value Items are compared by,
+// such as their UID or arg.
+func (fb *Feedback) Dedup(key func(it *Item) string) {
+	var (
+		items []*Item
+		seen  = map[string]bool{}
+	)
+	for _, it := range fb.Items {
+		k := key(it)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		items = append(items, it)
+	}
+	fb.Items = items
+}
+
+// SortPinned moves pinned Items (see Item.Pin()) to the top of the results.
+// The sort is stable, so pinned and unpinned Items each retain their order.
+//
+// This only sets the order in which Items are sent to Alfred. Alfred
+// may still rank Items the user has selected before above pinned Items,
+// unless those Items also have SkipKnowledge set.
+func (fb *Feedback) SortPinned() {
+	fb.SortFunc(func(a, b *Item) bool { return a.pinned && !b.pinned })
+}
+
 // Filter fuzzy-sorts Items against query and deletes Items that don't match.
+// If Feedback.MinScore is set, Items scoring less than it are also deleted.
 // It returns a slice of Result structs, which contain the results of the
 // fuzzy sorting.
 func (fb *Feedback) Filter(query string, opts ...fuzzy.Option) []*fuzzy.Result {
@@ -500,7 +1149,7 @@ func (fb *Feedback) Filter(query string, opts ...fuzzy.Option) []*fuzzy.Result {
 
 	r := fb.Sort(query, opts...)
 	for i, it := range fb.Items {
-		if r[i].Match {
+		if r[i].Match && (fb.MinScore == 0 || r[i].Score >= fb.MinScore) {
 			items = append(items, it)
 			res = append(res, r[i])
 		}
@@ -509,16 +1158,53 @@ func (fb *Feedback) Filter(query string, opts ...fuzzy.Option) []*fuzzy.Result {
 	return res
 }
 
+// MatchField is an Item field that fuzzy filtering can match against.
+// See Feedback.MatchOrder.
+type MatchField string
+
+// Fields for Feedback.MatchOrder and Item.MatchOrder.
+const (
+	FieldMatch    MatchField = "match"    // Item.Match()
+	FieldTitle    MatchField = "title"    // Item.Title()
+	FieldSubtitle MatchField = "subtitle" // Item.Subtitle()
+)
+
+// defaultMatchOrder is used if Feedback.MatchOrder is empty.
+var defaultMatchOrder = []MatchField{FieldMatch, FieldTitle}
+
 // Keywords implements fuzzy.Sortable.
 //
-// Returns the match or title field for Item i.
+// Returns the first non-empty field of Item i in the Item's or Feedback's
+// MatchOrder, by default the match or title field.
 func (fb *Feedback) Keywords(i int) string {
 	it := fb.Items[i]
-	// Sort on title if match isn't set
-	if it.match != nil {
-		return *it.match
+	order := it.matchOrder
+	if len(order) == 0 {
+		order = fb.MatchOrder
+	}
+	if len(order) == 0 {
+		order = defaultMatchOrder
+	}
+
+	for _, f := range order {
+		var s string
+		switch f {
+		case FieldMatch:
+			if it.match != nil {
+				s = *it.match
+			}
+		case FieldTitle:
+			s = it.title
+		case FieldSubtitle:
+			if it.subtitle != nil {
+				s = *it.subtitle
+			}
+		}
+		if s != "" {
+			return s
+		}
 	}
-	return it.title
+	return ""
 }
 
 // Len implements sort.Interface.
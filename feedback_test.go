@@ -4,8 +4,14 @@
 package aw
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -67,6 +73,9 @@ func TestItem_MarshalJSON(t *testing.T) {
 		// Valid item
 		{in: &Item{title: "title", valid: true},
 			x: `{"title":"title","valid":true}`},
+		// Skip knowledge
+		{in: &Item{title: "title", skip: true},
+			x: `{"title":"title","valid":false,"skipknowledge":true}`},
 		// With arg
 		{in: &Item{title: "title", arg: []string{"arg1"}},
 			x: `{"title":"title","arg":"arg1","valid":false}`},
@@ -97,6 +106,11 @@ func TestItem_MarshalJSON(t *testing.T) {
 		// With type = file
 		{in: &Item{title: "title", file: true},
 			x: `{"title":"title","valid":false,"type":"file"}`},
+		// With type = file:skipcheck
+		{in: (&Item{title: "title"}).IsFileSkipCheck(true),
+			x: `{"title":"title","valid":false,"type":"file:skipcheck"}`},
+		{in: (&Item{title: "title"}).IsFileSkipCheck(true).IsFile(true),
+			x: `{"title":"title","valid":false,"type":"file"}`},
 		// With copy text
 		{in: &Item{title: "title", copytext: p("copy")},
 			x: `{"title":"title","valid":false,"text":{"copy":"copy"}}`},
@@ -106,6 +120,19 @@ func TestItem_MarshalJSON(t *testing.T) {
 		// With copy and large text
 		{in: &Item{title: "title", copytext: p("copy"), largetype: p("large")},
 			x: `{"title":"title","valid":false,"text":{"copy":"copy","largetype":"large"}}`},
+		// With untyped action
+		{in: &Item{title: "title", action: &itemAction{auto: []string{"value"}}},
+			x: `{"title":"title","valid":false,"action":"value"}`},
+		// With multiple untyped actions
+		{in: &Item{title: "title", action: &itemAction{auto: []string{"one", "two"}}},
+			x: `{"title":"title","valid":false,"action":["one","two"]}`},
+		// With typed actions
+		{in: &Item{title: "title", action: &itemAction{
+			text: []string{"text"}, url: []string{"https://example.com"}, file: []string{"/a", "/b"}}},
+			x: `{"title":"title","valid":false,"action":{"text":"text","url":"https://example.com","file":["/a","/b"]}}`},
+		// With empty action
+		{in: &Item{title: "title", action: &itemAction{}},
+			x: `{"title":"title","valid":false}`},
 		// With arg and variable
 		{in: &Item{title: "title", arg: []string{"value"}, vars: map[string]string{"foo": "bar"}},
 			x: `{"title":"title","arg":"value","valid":false,"variables":{"foo":"bar"}}`},
@@ -115,6 +142,12 @@ func TestItem_MarshalJSON(t *testing.T) {
 		// With quicklook
 		{in: &Item{title: "title", ql: p("http://www.example.com")},
 			x: `{"title":"title","valid":false,"quicklookurl":"http://www.example.com"}`},
+		// With quicklook path
+		{in: &Item{title: "title", ql: p("/Applications/Safari.app")},
+			x: `{"title":"title","valid":false,"quicklookurl":"/Applications/Safari.app"}`},
+		// With empty quicklook
+		{in: &Item{title: "title", ql: p("")},
+			x: `{"title":"title","valid":false}`},
 	}
 
 	for i, td := range tests {
@@ -302,6 +335,21 @@ func TestModifierInheritVars(t *testing.T) {
 	assert.Equal(t, "bar", m.Vars()["foo"], "unexpected var value")
 }
 
+// Modifier variables override Item variables of the same name
+func TestModifierOverrideVars(t *testing.T) {
+	t.Parallel()
+
+	fb := NewFeedback()
+	it := fb.NewItem("title").Var("foo", "item").Var("bar", "item")
+	it.Cmd().Var("foo", "cmd")
+
+	x := `{"title":"title","valid":false,"variables":{"bar":"item","foo":"item"},` +
+		`"mods":{"cmd":{"variables":{"bar":"item","foo":"cmd"}}}}`
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, x, string(data), "unexpected JSON")
+}
+
 // Empty/invalid modifiers
 func TestEmptyModifiersIgnored(t *testing.T) {
 	t.Parallel()
@@ -353,11 +401,18 @@ func TestMultipleModifiers(t *testing.T) {
 		{[]ModKey{"opt"}, "alt"},
 		{[]ModKey{"fn"}, "fn"},
 		{[]ModKey{"shift"}, "shift"},
-		{[]ModKey{"alt", "cmd"}, "alt+cmd"},
-		{[]ModKey{"cmd", "alt"}, "alt+cmd"},
-		{[]ModKey{"cmd", "opt"}, "alt+cmd"},
-		{[]ModKey{"cmd", "opt", "ctrl"}, "alt+cmd+ctrl"},
-		{[]ModKey{"cmd", "opt", "shift"}, "alt+cmd+shift"},
+		{[]ModKey{"alt", "cmd"}, "cmd+alt"},
+		{[]ModKey{"cmd", "alt"}, "cmd+alt"},
+		{[]ModKey{"cmd", "opt"}, "cmd+alt"},
+		{[]ModKey{"cmd", "opt", "ctrl"}, "cmd+alt+ctrl"},
+		{[]ModKey{"cmd", "opt", "shift"}, "cmd+alt+shift"},
+		{[]ModKey{"shift", "ctrl", "alt", "cmd"}, "cmd+alt+ctrl+shift"},
+		// fn can't be combined
+		{[]ModKey{"fn", "shift", "ctrl", "alt", "cmd"}, "cmd+alt+ctrl+shift"},
+		{[]ModKey{"fn", "fn"}, "fn"},
+		// duplicates ignored
+		{[]ModKey{"cmd", "cmd"}, "cmd"},
+		{[]ModKey{"alt", "opt", "cmd"}, "cmd+alt"},
 		// invalid keys ignored
 		{[]ModKey{}, ""},
 		{[]ModKey{""}, ""},
@@ -396,6 +451,239 @@ func TestModifierShortcuts(t *testing.T) {
 	}
 }
 
+// Modifier shortcuts return existing Modifiers & can be chained.
+func TestModifierShortcuts_Chained(t *testing.T) {
+	t.Parallel()
+
+	it := &Item{title: "title"}
+	it.Cmd().Subtitle("cmd sub").Arg("cmd arg")
+	it.Cmd().Valid(true)
+	it.Alt().Subtitle("alt sub").Var("foo", "bar")
+
+	assert.Equal(t, it.Cmd(), it.mods[ModCmd], "shortcut didn't return existing Modifier")
+	assert.Equal(t, 2, len(it.mods), "unexpected modifier count")
+
+	x := `{"title":"title","valid":false,"mods":{` +
+		`"alt":{"subtitle":"alt sub","variables":{"foo":"bar"}},` +
+		`"cmd":{"arg":"cmd arg","subtitle":"cmd sub","valid":true}}}`
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, x, string(data), "unexpected JSON")
+
+	// NewModifier still replaces an existing Modifier
+	it.NewModifier(ModCmd)
+	assert.Nil(t, it.Cmd().subtitle, "NewModifier didn't replace Modifier")
+}
+
+// ArgFunc is called once, when Feedback is encoded.
+func TestItem_ArgFunc(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	fb := NewFeedback()
+	it := fb.NewItem("title").Valid(true).ArgFunc(func() (string, error) {
+		calls++
+		return "arg", nil
+	})
+	// MarshalJSON doesn't generate arg
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, `{"title":"title","valid":true}`, string(data), "unexpected JSON")
+	assert.Equal(t, 0, calls, "ArgFunc called early")
+
+	x := `{"title":"title","arg":"arg","valid":true}`
+	for i := 0; i < 2; i++ {
+		require.Nil(t, fb.Encode(ioutil.Discard), "encode Feedback failed")
+		data, err := json.Marshal(it)
+		require.Nil(t, err, "marshal Item failed")
+		assert.Equal(t, x, string(data), "unexpected JSON")
+	}
+	assert.Equal(t, 1, calls, "ArgFunc not called once")
+
+	// errors invalidate Item
+	fb = NewFeedback()
+	it = fb.NewItem("title").Valid(true).ArgFunc(func() (string, error) {
+		return "", errors.New("failed")
+	})
+	fb.resolveArgs()
+	data, err = json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, `{"title":"title","valid":false}`, string(data), "unexpected JSON")
+
+	// Arg replaces ArgFunc
+	fb = NewFeedback()
+	it = fb.NewItem("title").ArgFunc(func() (string, error) { return "func", nil }).Arg("static")
+	fb.resolveArgs()
+	data, err = json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, `{"title":"title","arg":"static","valid":false}`, string(data), "unexpected JSON")
+}
+
+// Append adds Items and variables from another Feedback.
+func TestFeedback_Append(t *testing.T) {
+	t.Parallel()
+
+	fb := NewFeedback().Var("a", "1")
+	fb.NewItem("one")
+	other := NewFeedback().Var("a", "other").Var("b", "2").Rerun(1)
+	other.NewItem("two")
+	other.NewItem("three")
+
+	assert.True(t, fb.Append(other) == fb, "Append didn't return Feedback")
+	var titles []string
+	for _, it := range fb.Items {
+		titles = append(titles, it.title)
+	}
+	assert.Equal(t, []string{"one", "two", "three"}, titles, "unexpected items")
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, fb.Vars(), "unexpected variables")
+	assert.Equal(t, 0.0, fb.rerun, "rerun copied")
+	assert.Equal(t, 2, len(other.Items), "other modified")
+
+	fb.Append(nil)
+	assert.Equal(t, 3, len(fb.Items), "nil Feedback appended")
+}
+
+// Mod returns existing Modifiers without creating new ones.
+func TestItem_Mod(t *testing.T) {
+	t.Parallel()
+
+	it := NewFeedback().NewItem("title")
+	cmd := it.Cmd().Subtitle("cmd")
+	combo := it.NewModifier(ModCmd, ModShift).Arg("combo")
+
+	m, ok := it.Mod(ModCmd)
+	assert.True(t, ok, "cmd modifier not found")
+	assert.True(t, m == cmd, "unexpected cmd modifier")
+	m, ok = it.Mod("shift", "cmd")
+	assert.True(t, ok, "cmd+shift modifier not found")
+	assert.True(t, m == combo, "unexpected cmd+shift modifier")
+
+	for _, keys := range [][]ModKey{{ModAlt}, {}, {"bogus"}} {
+		m, ok = it.Mod(keys...)
+		assert.False(t, ok, "found modifier for %v", keys)
+		assert.Nil(t, m, "unexpected modifier for %v", keys)
+	}
+	assert.Equal(t, 2, len(it.mods), "Mod created a Modifier")
+}
+
+// Modifiers inherit Item's subtitle only if requested.
+func TestModifier_InheritSubtitle(t *testing.T) {
+	t.Parallel()
+
+	it := &Item{title: "title"}
+	it.Cmd().InheritSubtitle().Valid(false)
+	it.Alt().InheritSubtitle().Subtitle("alt sub")
+	it.Ctrl().Arg("ctrl arg")
+
+	// no subtitle to inherit
+	x := `{"title":"title","valid":false,"mods":{` +
+		`"alt":{"subtitle":"alt sub"},"cmd":{"valid":false},"ctrl":{"arg":"ctrl arg"}}}`
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, x, string(data), "unexpected JSON")
+
+	it.Subtitle("sub")
+	x = `{"title":"title","subtitle":"sub","valid":false,"mods":{` +
+		`"alt":{"subtitle":"alt sub"},"cmd":{"subtitle":"sub","valid":false},"ctrl":{"arg":"ctrl arg"}}}`
+	data, err = json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, x, string(data), "unexpected JSON")
+	assert.Nil(t, it.Cmd().subtitle, "Modifier changed")
+}
+
+// Modifier validity is independent of Item validity.
+func TestModifier_Valid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		item, mod bool
+		x         string
+	}{
+		{true, true, `{"title":"title","arg":"arg","valid":true,"mods":{"cmd":{"valid":true}}}`},
+		{true, false, `{"title":"title","arg":"arg","valid":true,"mods":{"cmd":{"valid":false}}}`},
+		{false, true, `{"title":"title","arg":"arg","valid":false,"mods":{"cmd":{"valid":true}}}`},
+		{false, false, `{"title":"title","arg":"arg","valid":false,"mods":{"cmd":{"valid":false}}}`},
+	}
+	for _, td := range tests {
+		it := NewFeedback().NewItem("title").Arg("arg").Valid(td.item)
+		it.Cmd().Valid(td.mod)
+		data, err := json.Marshal(it)
+		require.Nil(t, err, "marshal Item failed")
+		assert.Equal(t, td.x, string(data), "unexpected JSON for item=%v, mod=%v", td.item, td.mod)
+	}
+
+	// unset valid is inherited from Item
+	it := NewFeedback().NewItem("title").Arg("arg").Valid(true)
+	it.Cmd().Subtitle("sub")
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, `{"title":"title","arg":"arg","valid":true,"mods":{"cmd":{"subtitle":"sub"}}}`,
+		string(data), "unexpected JSON")
+}
+
+// SetVars merges variables into Item's variables.
+func TestItem_SetVars(t *testing.T) {
+	t.Parallel()
+
+	it := &Item{}
+	it.SetVars(nil)
+	assert.Nil(t, it.Vars(), "variables set from nil map")
+
+	it.Var("foo", "bar").Var("a", "b")
+	it.SetVars(map[string]string{"foo": "baz", "c": "d"})
+	assert.Equal(t, map[string]string{"foo": "baz", "a": "b", "c": "d"}, it.Vars(), "unexpected variables")
+}
+
+// Subtitles sets Item and Modifier subtitles, ignoring empty strings.
+func TestItem_Subtitles(t *testing.T) {
+	t.Parallel()
+
+	it := &Item{title: "title"}
+	it.Alt().Arg("alt arg")
+	it.Subtitles("sub", "cmd sub", "alt sub", "", "shift sub")
+
+	assert.Equal(t, 3, len(it.mods), "unexpected modifier count")
+	x := `{"title":"title","subtitle":"sub","valid":false,"mods":{` +
+		`"alt":{"arg":"alt arg","subtitle":"alt sub"},` +
+		`"cmd":{"subtitle":"cmd sub"},` +
+		`"shift":{"subtitle":"shift sub"}}}`
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, x, string(data), "unexpected JSON")
+
+	// empty base subtitle is ignored
+	it.Subtitles("", "", "", "", "")
+	assert.Equal(t, "sub", *it.subtitle, "subtitle was changed")
+}
+
+// Items are matched against the fields in MatchOrder.
+func TestFeedback_MatchOrder(t *testing.T) {
+	t.Parallel()
+
+	fb := NewFeedback()
+	fb.NewItem("title 1").Subtitle("subtitle 1").Match("match 1")
+	fb.NewItem("title 2").Subtitle("subtitle 2")
+	fb.NewItem("").Subtitle("subtitle 3")
+	fb.NewItem("title 4").Match("match 4").MatchOrder(FieldSubtitle, FieldTitle)
+
+	tests := []struct {
+		order []MatchField
+		x     []string
+	}{
+		{nil, []string{"match 1", "title 2", "", "title 4"}},
+		{[]MatchField{FieldTitle, FieldSubtitle}, []string{"title 1", "title 2", "subtitle 3", "title 4"}},
+		{[]MatchField{FieldSubtitle}, []string{"subtitle 1", "subtitle 2", "subtitle 3", "title 4"}},
+	}
+	for _, td := range tests {
+		fb.MatchOrder = td.order
+		var v []string
+		for i := range fb.Items {
+			v = append(v, fb.Keywords(i))
+		}
+		assert.Equal(t, td.x, v, "unexpected keywords for %v", td.order)
+	}
+}
+
 // TestFeedback_Rerun verifies that rerun is properly set.
 func TestFeedback_Rerun(t *testing.T) {
 	t.Parallel()
@@ -410,6 +698,127 @@ func TestFeedback_Rerun(t *testing.T) {
 	assert.Equal(t, string(got), want, "unexpected value")
 }
 
+// TestFeedback_RerunClamped verifies that rerun is clamped to Alfred's range.
+func TestFeedback_RerunClamped(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in, x float64
+		js    string
+	}{
+		{0, 0, `{"items":[]}`},
+		{-1, 0, `{"items":[]}`},
+		{0.01, 0.1, `{"rerun":0.1,"items":[]}`},
+		{0.1, 0.1, `{"rerun":0.1,"items":[]}`},
+		{5, 5, `{"rerun":5,"items":[]}`},
+		{10, 5, `{"rerun":5,"items":[]}`},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(fmt.Sprint(td.in), func(t *testing.T) {
+			t.Parallel()
+			fb := NewFeedback().Rerun(td.in)
+			assert.Equal(t, td.x, fb.rerun, "unexpected rerun")
+			got, err := json.Marshal(fb)
+			assert.Nil(t, err, "marshal Feedback failed")
+			assert.Equal(t, td.js, string(got), "unexpected JSON")
+		})
+	}
+}
+
+// TestItem_IsFile verifies that file items without a path log a warning.
+func TestItem_IsFile(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	it := &Item{title: "file"}
+	it.Arg("/path/to/file").Valid(true).IsFile(true)
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, `{"title":"file","arg":"/path/to/file","valid":true,"type":"file"}`, string(data), "unexpected JSON")
+	assert.Equal(t, "", buf.String(), "unexpected warning")
+
+	it = &Item{title: "no path"}
+	it.IsFile(true)
+	data, err = json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, `{"title":"no path","valid":false,"type":"file"}`, string(data), "unexpected JSON")
+	assert.Contains(t, buf.String(), "no arg", "no warning logged")
+}
+
+// TestFeedback_Clear verifies that Clear resets Feedback.
+func TestFeedback_Clear(t *testing.T) {
+	t.Parallel()
+
+	// safe to call on empty Feedback
+	fb := &Feedback{}
+	fb.Clear()
+	assert.True(t, fb.IsEmpty(), "Feedback not empty")
+
+	fb = NewFeedback()
+	fb.Rerun(1).Var("foo", "bar")
+	fb.NewItem("item 1")
+	fb.NewItem("item 2")
+	assert.Equal(t, 2, len(fb.Items), "unexpected item count")
+
+	fb.Clear()
+	assert.True(t, fb.IsEmpty(), "Feedback not empty")
+	assert.Equal(t, 0.0, fb.rerun, "rerun not reset")
+	assert.Equal(t, map[string]string{}, fb.Vars(), "variables not reset")
+
+	got, err := json.Marshal(fb)
+	require.Nil(t, err, "marshal Feedback failed")
+	assert.Equal(t, `{"items":[]}`, string(got), "unexpected JSON")
+}
+
+// TestFeedback_SendTwice verifies that feedback can only be sent once.
+func TestFeedback_SendTwice(t *testing.T) {
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.Nil(t, err, "open devnull")
+	os.Stdout = devNull
+	defer func() {
+		os.Stdout = stdout
+		panicOnErr(devNull.Close())
+	}()
+
+	fb := NewFeedback()
+	fb.NewItem("item")
+	require.Nil(t, fb.Send(), "send feedback failed")
+	assert.Equal(t, ErrFeedbackSent, fb.Send(), "feedback sent twice")
+
+	fb.Reset()
+	assert.True(t, fb.IsEmpty(), "Feedback not empty")
+	assert.Nil(t, fb.Send(), "send reset feedback failed")
+}
+
+// Feedback is written to a Writer
+func TestFeedback_Encode(t *testing.T) {
+	t.Parallel()
+
+	fb := NewFeedback()
+	fb.NewItem("item").Arg("arg").Valid(true)
+
+	x := `{
+  "items": [
+    {
+      "title": "item",
+      "arg": "arg",
+      "valid": true
+    }
+  ]
+}`
+	// can be called repeatedly
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		require.Nil(t, fb.Encode(&buf), "encode feedback failed")
+		assert.Equal(t, x, buf.String(), "unexpected JSON")
+	}
+	assert.False(t, fb.sent, "Encode marked Feedback as sent")
+}
+
 // Vars are properly inherited by Items and Modifiers
 func TestFeedback_Vars(t *testing.T) {
 	t.Parallel()
@@ -489,6 +898,22 @@ func TestItem_methods(t *testing.T) {
 	assert.Equal(t, qlURL, *it.ql, "Bad quicklook URL")
 }
 
+// QuicklookURL sets quicklookurl, and the key is omitted if unset.
+func TestItem_QuicklookURL(t *testing.T) {
+	t.Parallel()
+
+	fb := NewFeedback()
+	it := fb.NewItem("title")
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.NotContains(t, string(data), "quicklookurl", "unset quicklookurl in JSON")
+
+	it.QuicklookURL("https://www.example.com")
+	data, err = json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Contains(t, string(data), `"quicklookurl":"https://www.example.com"`, "quicklookurl not in JSON")
+}
+
 func TestModifier_methods(t *testing.T) {
 	var (
 		key      = ModCmd
@@ -534,6 +959,134 @@ func TestFeedback_Sort(t *testing.T) {
 	}
 }
 
+// Sorts Feedback.Items with a custom function
+func TestFeedback_SortFunc(t *testing.T) {
+	t.Parallel()
+
+	byLen := func(a, b *Item) bool { return len(a.title) < len(b.title) }
+
+	fb := NewFeedback()
+	fb.SortFunc(byLen)
+	assert.True(t, fb.IsEmpty(), "empty feedback not empty")
+
+	for _, s := range []string{"three", "one", "four", "two", "eleven"} {
+		fb.NewItem(s)
+	}
+	titles := func() []string {
+		var l []string
+		for _, it := range fb.Items {
+			l = append(l, it.title)
+		}
+		return l
+	}
+
+	fb.SortFunc(byLen)
+	// sort is stable
+	assert.Equal(t, []string{"one", "two", "four", "three", "eleven"}, titles(), "unexpected order")
+
+	fb.SortFunc(func(a, b *Item) bool { return a.title < b.title })
+	assert.Equal(t, []string{"eleven", "four", "one", "three", "two"}, titles(), "unexpected order")
+}
+
+// Universal Action setters
+func TestItem_Action(t *testing.T) {
+	t.Parallel()
+
+	it := &Item{title: "title"}
+	it.Action("auto").ActionText("text").ActionURL("https://example.com").ActionFile("/a", "/b")
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	x := `{"title":"title","valid":false,"action":{"auto":"auto","text":"text",` +
+		`"url":"https://example.com","file":["/a","/b"]}}`
+	assert.Equal(t, x, string(data), "unexpected JSON")
+
+	// unset values
+	it.Action().ActionText().ActionURL()
+	data, err = json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, `{"title":"title","valid":false,"action":{"file":["/a","/b"]}}`, string(data), "unexpected JSON")
+}
+
+// Items with the same key are removed
+func TestFeedback_Dedup(t *testing.T) {
+	t.Parallel()
+
+	fb := NewFeedback()
+	fb.Dedup(func(it *Item) string { return it.title })
+	assert.True(t, fb.IsEmpty(), "empty feedback not empty")
+
+	for _, s := range []string{"one", "two", "one", "three", "two"} {
+		fb.NewItem(s).Subtitle(fmt.Sprintf("%d", len(fb.Items)))
+	}
+	fb.Dedup(func(it *Item) string { return it.title })
+	require.Equal(t, 3, len(fb.Items), "unexpected item count")
+	for i, s := range []string{"one", "two", "three"} {
+		assert.Equal(t, s, fb.Items[i].title, "unexpected title")
+	}
+	// first item is kept
+	assert.Equal(t, "1", *fb.Items[0].subtitle, "later duplicate kept")
+}
+
+// Items with same values are equal
+func TestItem_Equal(t *testing.T) {
+	t.Parallel()
+
+	newItem := func() *Item {
+		it := &Item{}
+		it.Title("title").Subtitle("sub").Arg("arg").Valid(true).Var("foo", "bar")
+		it.Cmd().Subtitle("cmd sub")
+		return it
+	}
+
+	a, b := newItem(), newItem()
+	assert.True(t, a.Equal(b), "equal items not equal")
+	b.Cmd().Arg("cmd arg")
+	assert.False(t, a.Equal(b), "unequal modifiers equal")
+	assert.False(t, a.Equal(newItem().Subtitle("other")), "unequal items equal")
+	assert.False(t, a.Equal(nil), "item equal to nil")
+	assert.True(t, (*Item)(nil).Equal(nil), "nil items not equal")
+
+	// inherited subtitle and explicit subtitle are the same
+	a, b = newItem(), newItem()
+	a.Alt().InheritSubtitle()
+	b.Alt().Subtitle("sub")
+	assert.True(t, a.Equal(b), "inherited subtitle not equal")
+	b.Alt().Valid(false)
+	assert.False(t, a.Equal(b), "explicit valid equal")
+
+	// lazy args aren't generated
+	var called bool
+	a, b = newItem(), newItem()
+	a.ArgFunc(func() (string, error) { called = true; return "arg", nil })
+	assert.False(t, a.Equal(b), "lazy arg equal")
+	assert.True(t, a.Equal(a), "item not equal to itself")
+	assert.False(t, called, "lazy arg generated")
+}
+
+// Pinned Items are sorted to the top
+func TestFeedback_SortPinned(t *testing.T) {
+	t.Parallel()
+
+	fb := NewFeedback()
+	fb.SortPinned()
+	for _, s := range []string{"one", "two", "three", "four", "five"} {
+		it := fb.NewItem(s)
+		if s == "three" || s == "five" {
+			it.Pin()
+		}
+	}
+
+	fb.SortPinned()
+	var titles []string
+	for _, it := range fb.Items {
+		titles = append(titles, it.title)
+	}
+	assert.Equal(t, []string{"three", "five", "one", "two", "four"}, titles, "unexpected order")
+	assert.True(t, fb.Items[0].IsPinned(), "item not pinned")
+	assert.True(t, fb.Items[0].skip, "pinned item doesn't skip knowledge")
+	assert.False(t, fb.Items[2].IsPinned(), "item pinned")
+}
+
 var feedbackTitles = []struct {
 	q   string
 	in  []string
@@ -603,3 +1156,119 @@ func TestFeedback_Filter(t *testing.T) {
 		}
 	}
 }
+
+// Filter drops Items scoring below MinScore
+func TestFeedback_Filter_MinScore(t *testing.T) {
+	for _, td := range filterTitles {
+		// get scores for matching items
+		fb := NewFeedback()
+		for _, s := range td.in {
+			fb.NewItem(s)
+		}
+		r := fb.Filter(td.q)
+		require.Equal(t, len(td.out), len(r), "unexpected result count")
+		min := r[0].Score
+
+		fb = NewFeedback()
+		fb.MinScore = min
+		for _, s := range td.in {
+			fb.NewItem(s)
+		}
+		r = fb.Filter(td.q)
+		require.Equal(t, len(r), len(fb.Items), "unexpected item count")
+		assert.Equal(t, td.out[0], fb.Items[0].title, "unexpected title")
+		for _, res := range r {
+			assert.True(t, res.Score >= min, "score lower than MinScore")
+		}
+	}
+}
+
+func TestItem_Args(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args []string
+		x    string
+	}{
+		{[]string{"one"}, `{"title":"title","arg":"one","valid":false}`},
+		{[]string{"one", "two"}, `{"title":"title","arg":["one","two"],"valid":false}`},
+	}
+
+	for _, td := range tests {
+		it := &Item{title: "title"}
+		it.Args(td.args...)
+		data, err := json.Marshal(it)
+		require.Nil(t, err, "marshal Item failed")
+		assert.Equal(t, td.x, string(data), "unexpected JSON")
+	}
+}
+
+func TestItem_Invalid(t *testing.T) {
+	t.Parallel()
+
+	it := NewFeedback().NewItem("title").Valid(true)
+	assert.True(t, it.IsValid(), "item not valid")
+
+	it.Invalid("not logged in")
+	assert.False(t, it.IsValid(), "item valid")
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, `{"title":"title","subtitle":"not logged in","valid":false}`, string(data), "unexpected JSON")
+}
+
+func TestItem_AutocompleteOnly(t *testing.T) {
+	t.Parallel()
+
+	it := NewFeedback().NewItem("title").Valid(true).AutocompleteOnly("query ")
+	data, err := json.Marshal(it)
+	require.Nil(t, err, "marshal Item failed")
+	assert.Equal(t, `{"title":"title","autocomplete":"query ","valid":false}`, string(data), "unexpected JSON")
+}
+
+// Validate Feedback.
+func TestFeedback_validate(t *testing.T) {
+	t.Parallel()
+
+	fb := NewFeedback()
+	fb.NewItem("OK").Arg("arg").UID("uid").Valid(true)
+	fb.NewItem("").Arg("arg")
+	fb.NewItem("no arg").Valid(true)
+	fb.NewItem("file").IsFile(true)
+	fb.NewItem("dupe").UID("uid")
+	fb.NewItem("icon").Icon(&Icon{Value: "", Type: "bogus"})
+	fb.NewItem("mod").Cmd().Valid(true)
+	fb.NewItem("func").ArgFunc(func() (string, error) { return "arg", nil }).Valid(true)
+	it := fb.NewItem("bad mod").Arg("arg")
+	it.mods = map[ModKey]*Modifier{"cmd+bogus": {Key: "cmd+bogus"}, "cmd+fn": {Key: "cmd+fn"}, "": {}}
+
+	x := []string{
+		`item 2: title is empty`,
+		`item 3 ("no arg"): arg is empty but valid=true`,
+		`item 4 ("file"): item is a file but arg (path) is empty`,
+		`item 5 ("dupe"): UID "uid" is also used by item 1`,
+		`item 6 ("icon"): icon has no value`,
+		`item 6 ("icon"): invalid icon type "bogus"`,
+		`item 7 ("mod"): arg of modifier "cmd" is empty but valid=true`,
+		`item 9 ("bad mod"): invalid modifier key ""`,
+		`item 9 ("bad mod"): invalid modifier key "cmd+bogus"`,
+		`item 9 ("bad mod"): invalid modifier key "cmd+fn"`,
+	}
+	problems := fb.validate()
+	sort.Strings(problems[len(problems)-3:]) // mods map is unordered
+	assert.Equal(t, x, problems, "unexpected problems")
+
+	// empty variable names
+	fb = NewFeedback()
+	fb.Var("", "empty")
+	assert.Equal(t, []string{`variable has empty name`}, fb.validate(), "unexpected problems")
+	fb = NewFeedback()
+	fb.NewItem("var").Arg("arg").Var("", "empty")
+	assert.Equal(t, []string{`item 1 ("var"): variable has empty name`}, fb.validate(), "unexpected problems")
+
+	// valid modifier keys
+	fb = NewFeedback()
+	fb.NewItem("mods").Arg("arg").NewModifier("cmd", "alt", "shift")
+	fb.Items[0].Ctrl()
+	assert.Nil(t, fb.validate(), "valid modifiers have problems")
+	assert.Nil(t, NewFeedback().validate(), "empty feedback has problems")
+}
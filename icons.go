@@ -88,3 +88,11 @@ type Icon struct {
 	Value string   `json:"path"`           // Path or UTI
 	Type  IconType `json:"type,omitempty"` // "fileicon", "filetype" or ""
 }
+
+// IconFromFile returns an Icon that displays the icon of the file or
+// directory at path, e.g. "/Applications/Mail.app".
+func IconFromFile(path string) *Icon { return &Icon{path, IconTypeFileIcon} }
+
+// IconFromType returns an Icon that displays the icon macOS uses for
+// the given UTI, e.g. "com.adobe.pdf".
+func IconFromType(uti string) *Icon { return &Icon{uti, IconTypeFileType} }
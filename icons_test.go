@@ -4,6 +4,7 @@
 package aw
 
 import (
+	"encoding/json"
 	"os"
 	"testing"
 
@@ -52,3 +53,26 @@ func TestIcons(t *testing.T) {
 		})
 	}
 }
+
+// IconFromFile and IconFromType set the correct icon type.
+func TestIconFrom(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		icon *Icon
+		x    string
+	}{
+		{IconFromFile("/Applications/Mail.app"), `{"path":"/Applications/Mail.app","type":"fileicon"}`},
+		{IconFromType("com.adobe.pdf"), `{"path":"com.adobe.pdf","type":"filetype"}`},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.x, func(t *testing.T) {
+			t.Parallel()
+			data, err := json.Marshal(td.icon)
+			assert.Nil(t, err, "marshal Icon failed")
+			assert.Equal(t, td.x, string(data), "unexpected JSON")
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package semver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersion_Compare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b Version
+		x    int
+	}{
+		{Version{1, 0, 0, ""}, Version{1, 0, 0, ""}, 0},
+		{Version{1, 0, 1, ""}, Version{1, 0, 0, ""}, 1},
+		{Version{1, 10, 0, ""}, Version{1, 9, 0, ""}, 1},
+		{Version{2, 0, 0, ""}, Version{10, 0, 0, ""}, -1},
+		{Version{1, 0, 0, "beta"}, Version{1, 0, 0, ""}, -1},
+		{Version{1, 0, 0, "alpha"}, Version{1, 0, 0, "beta"}, -1},
+		{Version{1, 0, 0, "beta.2"}, Version{1, 0, 0, "beta.10"}, -1},
+		{Version{1, 0, 0, "beta"}, Version{1, 0, 0, "beta.1"}, -1},
+		{Version{1, 0, 0, "1"}, Version{1, 0, 0, "alpha"}, -1},
+		{Version{1, 0, 0, "rc1"}, Version{1, 0, 0, "rc2"}, -1},
+	}
+	for _, td := range tests {
+		td := td
+		t.Run(fmt.Sprintf("%v", td.a), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.x, td.a.Compare(td.b), "unexpected result for %v vs %v", td.a, td.b)
+			assert.Equal(t, -td.x, td.b.Compare(td.a), "unexpected result for %v vs %v", td.b, td.a)
+		})
+	}
+}
@@ -4,8 +4,10 @@
 package aw
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 )
 
@@ -25,6 +27,11 @@ action is executed, and the workflow exits.
 If no exact match is found, AwGo runs a Script Filter for the user to
 select an action. Hitting TAB or RETURN on an item will run it.
 
+If the first argument is "--", magic actions are disabled, so a query can
+start with the magic prefix. "--" itself is removed from the arguments
+returned by Workflow.Args(), i.e. ["--", "workflow:foo"] becomes
+["workflow:foo"]. A "--" anywhere else is a normal argument.
+
 Magic Actions are mainly aimed at making debugging and supporting users easier
 (via the built-in actions), but they also provide a simple way to integrate
 your own commands that don't need a "real" UI.
@@ -47,11 +54,17 @@ default:
 	<prefix>deldata     Delete everything in the workflow's data directory.
 	<prefix>delcache    Delete everything in the workflow's cache directory.
 	<prefix>reset       Delete everything in the workflow's data and cache directories.
+	<prefix>env         Log the workflow's environment variables and open
+	                    the log file. Secrets are redacted (see Config.Dump).
 	<prefix>help        Open help URL in default browser.
 	                    Only registered if you have set a HelpURL.
 	<prefix>update      Check for updates and install a newer version of the
 	                    workflow if available.
 	                    Only registered if you have configured an Updater.
+	<prefix>checkupdate Check for updates now, regardless of when the last
+	                    check was, and show whether one is available in
+	                    a notification.
+	                    Only registered if you have configured an Updater.
 
 
 Custom Actions
@@ -59,7 +72,12 @@ Custom Actions
 To add custom magicActions, you must register them with your Workflow
 *before* you call Workflow.Args()
 
-To do this, configure Workflow with the AddMagic option.
+To do this, configure Workflow with the AddMagic option. Each keyword
+should only be registered once: AddMagic replaces an action with the same
+keyword and logs a warning, or panics if Alfred's debugger is open.
+Workflow.RegisterMagic returns an error wrapping ErrMagicExists instead.
+To replace a built-in action, remove it first with the RemoveMagicKeyword
+option.
 
 */
 type MagicAction interface {
@@ -88,8 +106,35 @@ type magicActions struct {
 	wf      *Workflow
 }
 
-// register adds a MagicAction to the mapping. Previous entries are overwritten.
-func (ma *magicActions) register(actions ...MagicAction) {
+// ErrMagicExists is returned when registering a MagicAction whose keyword
+// is already taken by another action.
+var ErrMagicExists = errors.New("magic action already registered")
+
+// register adds MagicActions to the mapping. It returns an error wrapping
+// ErrMagicExists if a keyword is already registered, in which case
+// none of actions is added.
+func (ma *magicActions) register(actions ...MagicAction) error {
+	seen := map[string]bool{}
+	for _, action := range actions {
+		kw := action.Keyword()
+		if _, ok := ma.actions[kw]; ok || seen[kw] {
+			return fmt.Errorf("%w: %q", ErrMagicExists, kw)
+		}
+		seen[kw] = true
+	}
+	ma.replace(actions...)
+	return nil
+}
+
+// RegisterMagic registers Magic Actions with the Workflow, like the AddMagic
+// Option. If the keyword of one of actions is already registered, it
+// returns an error wrapping ErrMagicExists and none of actions is added.
+func (wf *Workflow) RegisterMagic(actions ...MagicAction) error {
+	return wf.magicActions.register(actions...)
+}
+
+// replace adds MagicActions to the mapping. Previous entries are overwritten.
+func (ma *magicActions) replace(actions ...MagicAction) {
 	for _, action := range actions {
 		ma.actions[action.Keyword()] = action
 	}
@@ -98,10 +143,18 @@ func (ma *magicActions) register(actions ...MagicAction) {
 // unregister removes a MagicAction from the mapping (based on its keyword).
 func (ma *magicActions) unregister(actions ...MagicAction) {
 	for _, action := range actions {
-		delete(ma.actions, action.Keyword())
+		ma.unregisterKeyword(action.Keyword())
 	}
 }
 
+// unregisterKeyword removes the MagicAction registered for keyword and
+// returns it. It returns nil if no action is registered for keyword.
+func (ma *magicActions) unregisterKeyword(keyword string) MagicAction {
+	action := ma.actions[keyword]
+	delete(ma.actions, keyword)
+	return action
+}
+
 // args runs a magic action or returns command-line arguments.
 // It parses args for magic actions. If it finds one, it takes
 // control of your workflow and runs the action. Control is
@@ -112,6 +165,7 @@ func (ma *magicActions) args(args []string, prefix string) []string {
 	args, handled := ma.handleArgs(args, prefix)
 
 	if handled {
+		ma.wf.runExitHooks()
 		finishLog(false)
 		exitFunc(0)
 	}
@@ -121,9 +175,16 @@ func (ma *magicActions) args(args []string, prefix string) []string {
 
 // handleArgs checks args for the magic prefix. Returns args and true if
 // it found and handled a magic argument.
+//
+// If the first argument is "--", magic arguments are ignored and "--" is
+// removed from the returned args.
 func (ma *magicActions) handleArgs(args []string, prefix string) ([]string, bool) {
 	var handled bool
 
+	if len(args) > 0 && args[0] == "--" {
+		return args[1:], false
+	}
+
 	for _, arg := range args {
 		arg = strings.TrimSpace(arg)
 
@@ -132,9 +193,11 @@ func (ma *magicActions) handleArgs(args []string, prefix string) ([]string, bool
 			action := ma.actions[query]
 
 			if action != nil {
-				log.Print(action.RunText())
+				ma.wf.debugf("running magic action %q", action.Keyword())
+				text := action.RunText()
+				log.Print(text)
 
-				ma.wf.NewItem(action.RunText()).
+				ma.wf.NewItem(text).
 					Icon(IconInfo).
 					Valid(false)
 
@@ -147,6 +210,7 @@ func (ma *magicActions) handleArgs(args []string, prefix string) ([]string, bool
 
 				handled = true
 			} else {
+				ma.wf.debugf("showing magic actions matching %q", query)
 				for kw, action := range ma.actions {
 					ma.wf.NewItem(action.Keyword()).
 						Subtitle(action.Description()).
@@ -229,6 +293,29 @@ func (a resetMA) Description() string { return "Delete all saved and cached work
 func (a resetMA) RunText() string     { return "Deleted workflow saved and cached data" }
 func (a resetMA) Run() error          { return a.wf.Reset() }
 
+// Logs workflow's variables and opens the log file.
+type envMA struct {
+	wf *Workflow
+}
+
+func (a envMA) Keyword() string     { return "env" }
+func (a envMA) Description() string { return "Log workflow's environment variables" }
+func (a envMA) RunText() string     { return "Logging environment…" }
+func (a envMA) Run() error {
+	env := a.wf.Config.Dump()
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	log.Printf("%d variable(s):", len(keys))
+	for _, k := range keys {
+		log.Printf("%s=%q", k, env[k])
+	}
+	return a.wf.OpenLog()
+}
+
 // Opens URL in default browser.
 type helpMA struct {
 	wf *Workflow
@@ -259,3 +346,39 @@ func (a updateMA) Run() error {
 	log.Println("No update available")
 	return nil
 }
+
+// Checks for a newer version of the workflow, ignoring the update interval.
+type checkUpdateMA struct {
+	updater Updater
+	notify  func(title, message string) error // reports result of check
+}
+
+func (a *checkUpdateMA) Keyword() string     { return "checkupdate" }
+func (a *checkUpdateMA) Description() string { return "Check for a newer version of the workflow now" }
+func (a *checkUpdateMA) RunText() string     { return "Checking for update…" }
+
+// Run checks for an update and reports the result in a notification,
+// as the Script Filter's feedback has already been sent.
+func (a *checkUpdateMA) Run() error {
+	if err := a.updater.CheckForUpdate(); err != nil {
+		a.report("Update check failed", err.Error())
+		return fmt.Errorf("check for update: %w", err)
+	}
+	msg := "Workflow is up to date"
+	if a.updater.UpdateAvailable() {
+		msg = "A newer version is available"
+	}
+	log.Print(msg)
+	a.report(msg, "")
+	return nil
+}
+
+// report shows a notification if notify is set.
+func (a *checkUpdateMA) report(title, message string) {
+	if a.notify == nil {
+		return
+	}
+	if err := a.notify(title, message); err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+}
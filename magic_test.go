@@ -10,6 +10,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.deanishe.net/env"
 )
 
 // Mock magic action
@@ -97,6 +99,14 @@ func TestNonMagicArgs(t *testing.T) {
 		in, x []string
 	}{
 		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		// "--" disables magic arguments
+		{[]string{"--", "workflow:foo"}, []string{"workflow:foo"}},
+		{[]string{"--", "workflow:log"}, []string{"workflow:log"}},
+		{[]string{"--"}, []string{}},
+		// "--" is only special as first argument
+		{[]string{"a", "--", "b"}, []string{"a", "--", "b"}},
+		{[]string{"a", "--"}, []string{"a", "--"}},
+		{[]string{" --", "b"}, []string{" --", "b"}},
 	}
 
 	for _, td := range data {
@@ -120,7 +130,7 @@ func TestMagicDefaults(t *testing.T) {
 		wf.Configure(HelpURL(helpURL))
 		ma := wf.magicActions
 
-		x := 7
+		x := 8
 		v := len(ma.actions)
 		if v != x {
 			t.Errorf("Bad MagicAction count. Expected=%d, Got=%d", x, v)
@@ -135,6 +145,7 @@ func TestMagicDefaults(t *testing.T) {
 			{"workflow:log", "open", []string{"open", wf.LogFile()}},
 			{"workflow:data", "open", []string{"open", wf.DataDir()}},
 			{"workflow:help", "open", []string{"open", helpURL}},
+			{"workflow:env", "open", []string{"open", wf.LogFile()}},
 		}
 
 		for _, td := range tests {
@@ -167,7 +178,7 @@ func TestMagicActions(t *testing.T) {
 				ta = &mockMA{}
 			)
 			exitFunc = func(int) {}
-			wf.magicActions.register(ta)
+			require.Nil(t, wf.magicActions.register(ta), "register action failed")
 			_ = wf.magicActions.args([]string{td.in}, DefaultMagicPrefix)
 			if err := ta.ValidateShown(); err != nil && td.shown {
 				t.Error("Not Shown")
@@ -179,6 +190,54 @@ func TestMagicActions(t *testing.T) {
 	}
 }
 
+// Registering a keyword twice fails.
+func TestMagicKeywordCollision(t *testing.T) {
+	t.Parallel()
+
+	wf := New()
+	a1, a2 := &mockMA{}, &mockMA{}
+	require.Nil(t, wf.magicActions.register(a1), "register action failed")
+	err := wf.magicActions.register(a2)
+	assert.True(t, errors.Is(err, ErrMagicExists), "unexpected error: %v", err)
+	assert.Equal(t, a1, wf.magicActions.actions["test"], "action replaced")
+
+	// duplicates within one call
+	wf.magicActions.unregister(a1)
+	err = wf.magicActions.register(a1, a2)
+	assert.True(t, errors.Is(err, ErrMagicExists), "unexpected error: %v", err)
+	assert.Nil(t, wf.magicActions.actions["test"], "action registered")
+
+	// RegisterMagic returns an error
+	err = wf.RegisterMagic(logMA{})
+	assert.True(t, errors.Is(err, ErrMagicExists), "unexpected error: %v", err)
+
+	// AddMagic panics on collision in debug mode
+	cfg := wf.Config
+	wf.Config = NewConfig(env.MapEnv{EnvVarDebug: "1"})
+	assert.Panics(t, func() { wf.Configure(AddMagic(logMA{})) }, "duplicate keyword accepted")
+	wf.Config = cfg
+
+	// removed keyword can be re-registered
+	prev := wf.Configure(RemoveMagicKeyword("log"))
+	assert.Nil(t, wf.magicActions.actions["log"], "action not removed")
+	assert.NotPanics(t, func() { wf.Configure(AddMagic(a1, &mockMA{keyword: "log"})) }, "register failed")
+	wf.Configure(RemoveMagic(a1, &mockMA{keyword: "log"}))
+	// restore original action
+	wf.Configure(prev)
+	assert.Equal(t, logMA{wf}, wf.magicActions.actions["log"], "action not restored")
+}
+
+// AddMagic replaces actions if the debugger isn't open.
+func TestAddMagic_Replace(t *testing.T) {
+	t.Parallel()
+
+	wf := New()
+	wf.Config = NewConfig(env.MapEnv{EnvVarDebug: "0"})
+	a := &mockMA{keyword: "log"}
+	assert.NotPanics(t, func() { wf.Configure(AddMagic(a)) }, "AddMagic panicked")
+	assert.Equal(t, a, wf.magicActions.actions["log"], "action not replaced")
+}
+
 // Test MagicArgs call os.Exit.
 func TestMagicExits(t *testing.T) {
 	tests := []struct {
@@ -197,8 +256,11 @@ func TestMagicExits(t *testing.T) {
 		withTestWf(func(wf *Workflow) {
 			me := &mockExit{}
 			exitFunc = me.Exit
+			var called bool
+			wf.OnExit(func() { called = true })
 			wf.magicActions.args([]string{td.in}, "prefix:")
 			assert.Equal(t, 0, me.code, "MagicArgs did not exit")
+			assert.Equal(t, td.exit, called, "unexpected exit function call")
 		})
 	}
 
@@ -242,3 +304,31 @@ func TestMagicUpdate(t *testing.T) {
 	assert.True(t, u.updateAvailableCalled, "UpdateAvailable not called")
 	assert.True(t, u.installCalled, "Install not called")
 }
+
+// Test automatically-added checkUpdateMA.
+func TestMagicCheckUpdate(t *testing.T) {
+	t.Parallel()
+
+	u := &mockUpdater{}
+	wf := New(Update(u))
+	a, ok := wf.magicActions.actions["checkupdate"].(*checkUpdateMA)
+	require.True(t, ok, "checkupdate action not registered")
+
+	var notified, message string
+	a.notify = func(title, msg string) error { notified, message = title, msg; return nil }
+
+	assert.Equal(t, "Checking for update…", a.RunText(), "unexpected RunText")
+	assert.False(t, u.checkForUpdateCalled, "RunText checked for update")
+	assert.Nil(t, a.Run(), "unexpected error")
+	assert.True(t, u.checkForUpdateCalled, "CheckForUpdate not called")
+	assert.True(t, u.updateAvailableCalled, "UpdateAvailable not called")
+	assert.Equal(t, "A newer version is available", notified, "unexpected notification")
+	assert.False(t, u.installCalled, "Install called")
+
+	u.checkShouldFail = true
+	err := a.Run()
+	require.NotNil(t, err, "check error not returned")
+	assert.Equal(t, "Update check failed", notified, "failure not notified")
+	assert.NotEqual(t, "", message, "failure notification has no message")
+	assert.Contains(t, err.Error(), message, "notification doesn't describe error")
+}
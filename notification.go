@@ -0,0 +1,42 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/deanishe/awgo/util"
+)
+
+// JXA script to post a notification. Arguments are title, subtitle
+// and message.
+const jxaNotify = `function run(argv) {
+	const app = Application.currentApplication()
+	app.includeStandardAdditions = true
+	app.displayNotification(argv[2], {withTitle: argv[0], subtitle: argv[1]})
+}`
+
+// mockable notification poster
+var postNotification = func(title, subtitle, message string) error {
+	_, err := util.RunJS(jxaNotify, title, subtitle, message)
+	return err
+}
+
+// SendNotification posts a macOS notification with the given title and
+// message. The workflow's name is shown as the notification's subtitle.
+//
+// An error is returned if the notification couldn't be posted. macOS
+// doesn't report whether notifications are shown, so if the user has
+// disabled notifications from Script Editor (which posts them), they
+// are silently dropped.
+func (wf *Workflow) SendNotification(title, message string) error {
+	if title == "" && message == "" {
+		return errors.New("empty notification")
+	}
+	if err := postNotification(title, wf.Name(), message); err != nil {
+		return fmt.Errorf("send notification %q: %w", title, err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Not parallel because it replaces postNotification.
+func TestWorkflow_SendNotification(t *testing.T) {
+	orig := postNotification
+	defer func() { postNotification = orig }()
+
+	var got []string
+	postNotification = func(title, subtitle, message string) error {
+		got = []string{title, subtitle, message}
+		return nil
+	}
+
+	withTestWf(func(wf *Workflow) {
+		require.Nil(t, wf.SendNotification("Done", "Updated 5 items"), "send notification failed")
+		assert.Equal(t, []string{"Done", tName, "Updated 5 items"}, got, "unexpected notification")
+
+		assert.NotNil(t, wf.SendNotification("", ""), "sent empty notification")
+
+		postNotification = func(title, subtitle, message string) error {
+			return errors.New("osascript failed")
+		}
+		err := wf.SendNotification("Done", "")
+		require.NotNil(t, err, "failure not reported")
+		assert.Contains(t, err.Error(), "osascript failed", "error not wrapped")
+	})
+}
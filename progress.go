@@ -0,0 +1,176 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// Progress passes the status of a background job to the Script Filter
+// that started it. The job calls Set() to report its progress, and the
+// Script Filter calls Workflow.ShowProgress() to display it.
+//
+// Progress is stored in a file in AwGo's cache directory, so the job and
+// the Script Filter must be run with the same workflow environment.
+type Progress struct {
+	jobName string
+	cache   *Cache
+}
+
+// progressStatus is the data stored by Progress.
+type progressStatus struct {
+	Percent float64   `json:"percent"`
+	Message string    `json:"message"`
+	Updated time.Time `json:"updated"`
+}
+
+// Progress returns a Progress for the named background job.
+func (wf *Workflow) Progress(jobName string) *Progress {
+	return &Progress{
+		jobName: jobName,
+		cache:   NewCache(filepath.Join(wf.awCacheDir(), "jobs")),
+	}
+}
+
+// Set saves the job's progress. pct is a percentage and is clamped to
+// the range 0-100. msg describes what the job is doing, e.g. "Loading…".
+func (p *Progress) Set(pct float64, msg string) error {
+	if pct < 0 {
+		pct = 0
+	}
+	if pct > 100 {
+		pct = 100
+	}
+	st := progressStatus{Percent: pct, Message: msg, Updated: time.Now()}
+	return p.cache.StoreJSON(p.name(), st)
+}
+
+// Get returns the job's last saved progress. If the job hasn't saved any
+// progress, pct is 0 and msg is empty.
+func (p *Progress) Get() (pct float64, msg string, err error) {
+	var st progressStatus
+	if err := p.cache.LoadJSON(p.name(), &st); err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			return 0, "", nil
+		}
+		return 0, "", fmt.Errorf("load progress of job %q: %w", p.jobName, err)
+	}
+	return st.Percent, st.Message, nil
+}
+
+// Clear deletes the job's saved progress and the record that
+// RunWithProgress started the job, so the next call to RunWithProgress
+// starts the job again.
+func (p *Progress) Clear() error {
+	if err := p.clearStatus(); err != nil {
+		return err
+	}
+	return p.cache.Store(p.marker(), nil)
+}
+
+// clearStatus deletes the job's saved progress.
+func (p *Progress) clearStatus() error { return p.cache.StoreJSON(p.name(), nil) }
+
+func (p *Progress) name() string { return p.jobName + ".progress.json" }
+
+// marker is the name of the file that records that RunWithProgress
+// started the job.
+func (p *Progress) marker() string { return p.jobName + ".started" }
+
+// ShowProgress displays the progress of the named background job.
+//
+// If the job is running, ShowProgress adds a single Item showing the job's
+// progress, e.g. "Loading… (42%)", sets Rerun so Alfred updates it, and
+// returns true. You should then send feedback without adding any other
+// Items.
+//
+// If the job isn't running, ShowProgress clears Rerun and the job's saved
+// progress and returns false, so you can show the job's results:
+//
+//	if wf.ShowProgress("fetch") {
+//		wf.SendFeedback()
+//		return
+//	}
+//	// show results of job
+func (wf *Workflow) ShowProgress(jobName string) bool {
+	p := wf.Progress(jobName)
+	if !wf.IsRunning(jobName) {
+		wf.Rerun(0)
+		if err := p.clearStatus(); err != nil {
+			log.Printf("[ERROR] clear progress of job %q: %v", jobName, err)
+		}
+		return false
+	}
+
+	pct, msg, err := p.Get()
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+	if msg == "" {
+		msg = "Working…"
+	}
+	wf.NewItem(fmt.Sprintf("%s (%.0f%%)", msg, pct)).
+		Icon(IconSync).
+		Valid(false)
+	wf.Rerun(0.3)
+	return true
+}
+
+// RunWithProgress runs a background job and shows its progress until it
+// has finished. start is called to start the job, usually by calling
+// RunInBackground with the same jobName.
+//
+// If the job hasn't been started, RunWithProgress calls start, adds a
+// "Starting…" Item, sets Rerun and returns true. If the job is running,
+// it shows the job's progress (see ShowProgress) and returns true. In both
+// cases, send feedback without adding any other Items.
+//
+// When the job has finished, RunWithProgress clears Rerun and returns
+// false, so you can show the job's results. It keeps returning false
+// without starting the job again until you call Progress.Clear(), e.g.
+// when the job's results are out of date:
+//
+//	if wf.Cache.Expired("results.json", time.Hour) {
+//		if err := wf.Progress("fetch").Clear(); err != nil {
+//			wf.FatalError(err)
+//		}
+//	}
+//
+//	busy, err := wf.RunWithProgress("fetch", func() error {
+//		return wf.RunInBackground("fetch", exec.Command(os.Args[0], "fetch"))
+//	})
+//	if err != nil {
+//		wf.FatalError(err)
+//	}
+//	if busy {
+//		wf.SendFeedback()
+//		return
+//	}
+//	// show results of job
+func (wf *Workflow) RunWithProgress(jobName string, start func() error) (busy bool, err error) {
+	p := wf.Progress(jobName)
+	if wf.IsRunning(jobName) {
+		return wf.ShowProgress(jobName), nil
+	}
+	if p.cache.Exists(p.marker()) {
+		// job has finished
+		return wf.ShowProgress(jobName), nil
+	}
+
+	if err := start(); err != nil && !IsJobExists(err) {
+		return false, fmt.Errorf("start job %q: %w", jobName, err)
+	}
+	if err := p.cache.Store(p.marker(), []byte{}); err != nil {
+		return false, fmt.Errorf("save marker of job %q: %w", jobName, err)
+	}
+	wf.NewItem("Starting…").
+		Icon(IconSync).
+		Valid(false)
+	wf.Rerun(0.3)
+	return true, nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		p := wf.Progress("test")
+		pct, msg, err := p.Get()
+		require.Nil(t, err, "get unset progress failed")
+		assert.Equal(t, 0.0, pct, "unexpected percent")
+		assert.Equal(t, "", msg, "unexpected message")
+
+		tests := []struct {
+			in, x float64
+		}{
+			{42, 42},
+			{-1, 0},
+			{150, 100},
+		}
+		for _, td := range tests {
+			require.Nil(t, p.Set(td.in, "Loading…"), "set progress failed")
+			pct, msg, err = wf.Progress("test").Get()
+			require.Nil(t, err, "get progress failed")
+			assert.Equal(t, td.x, pct, "unexpected percent")
+			assert.Equal(t, "Loading…", msg, "unexpected message")
+		}
+
+		require.Nil(t, p.Clear(), "clear progress failed")
+		pct, _, err = p.Get()
+		require.Nil(t, err, "get cleared progress failed")
+		assert.Equal(t, 0.0, pct, "progress not cleared")
+	})
+}
+
+func TestWorkflow_ShowProgress(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		jobName := "progress"
+		// job not running
+		assert.False(t, wf.ShowProgress(jobName), "progress shown for stopped job")
+		assert.True(t, wf.Feedback.IsEmpty(), "progress item added")
+
+		require.Nil(t, wf.RunInBackground(jobName, exec.Command("sleep", "5")), "start job failed")
+		defer wf.Kill(jobName)
+		require.Nil(t, wf.Progress(jobName).Set(42, "Loading…"), "set progress failed")
+
+		assert.True(t, wf.ShowProgress(jobName), "progress not shown")
+		require.Equal(t, 1, len(wf.Feedback.Items), "unexpected item count")
+		assert.Equal(t, "Loading… (42%)", wf.Feedback.Items[0].title, "unexpected title")
+		assert.Equal(t, 0.3, wf.Feedback.rerun, "rerun not set")
+
+		// job finished
+		require.Nil(t, wf.Kill(jobName), "kill job failed")
+		wf.Feedback.Clear()
+		wf.Rerun(1)
+		assert.False(t, wf.ShowProgress(jobName), "progress shown for finished job")
+		assert.True(t, wf.Feedback.IsEmpty(), "progress item added")
+		assert.Equal(t, 0.0, wf.Feedback.rerun, "rerun not cleared")
+	})
+}
+
+func TestWorkflow_RunWithProgress(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		var (
+			jobName = "progress"
+			started int
+			start   = func() error {
+				started++
+				return wf.RunInBackground(jobName, exec.Command("sleep", "5"))
+			}
+		)
+		defer wf.Kill(jobName)
+
+		// start job
+		busy, err := wf.RunWithProgress(jobName, start)
+		require.Nil(t, err, "RunWithProgress failed")
+		assert.True(t, busy, "job not started")
+		assert.Equal(t, 1, started, "start not called")
+		require.Equal(t, 1, len(wf.Feedback.Items), "unexpected item count")
+		assert.Equal(t, "Starting…", wf.Feedback.Items[0].title, "unexpected title")
+		assert.Equal(t, 0.3, wf.Feedback.rerun, "rerun not set")
+
+		// job running
+		wf.Feedback.Clear()
+		busy, err = wf.RunWithProgress(jobName, start)
+		require.Nil(t, err, "RunWithProgress failed")
+		assert.True(t, busy, "running job not shown")
+		assert.Equal(t, 1, started, "job started twice")
+		require.Equal(t, 1, len(wf.Feedback.Items), "unexpected item count")
+		assert.Equal(t, "Working… (0%)", wf.Feedback.Items[0].title, "unexpected title")
+
+		// job finished
+		require.Nil(t, wf.Kill(jobName), "kill job failed")
+		wf.Feedback.Clear()
+		wf.Rerun(1)
+		busy, err = wf.RunWithProgress(jobName, start)
+		require.Nil(t, err, "RunWithProgress failed")
+		assert.False(t, busy, "finished job shown")
+		assert.Equal(t, 1, started, "finished job restarted")
+		assert.True(t, wf.Feedback.IsEmpty(), "progress item added")
+		assert.Equal(t, 0.0, wf.Feedback.rerun, "rerun not cleared")
+
+		// results are shown until progress is cleared
+		busy, err = wf.RunWithProgress(jobName, start)
+		require.Nil(t, err, "RunWithProgress failed")
+		assert.False(t, busy, "finished job shown")
+		assert.Equal(t, 1, started, "finished job restarted")
+		assert.True(t, wf.Feedback.IsEmpty(), "progress item added")
+
+		require.Nil(t, wf.Progress(jobName).Clear(), "clear progress failed")
+		busy, err = wf.RunWithProgress(jobName, start)
+		require.Nil(t, err, "RunWithProgress failed")
+		assert.True(t, busy, "job not restarted")
+		assert.Equal(t, 2, started, "start not called")
+		require.Nil(t, wf.Kill(jobName), "kill job failed")
+
+		// start fails
+		require.Nil(t, wf.Progress(jobName).Clear(), "clear progress failed")
+		_, err = wf.RunWithProgress(jobName, func() error { return errors.New("failed") })
+		assert.NotNil(t, err, "start error not returned")
+	})
+}
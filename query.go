@@ -0,0 +1,78 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"sort"
+	"strings"
+)
+
+// Query is a Script Filter query split into flags and search text.
+// Create one with ParseQuery() or Workflow.ParseQuery().
+type Query struct {
+	Text  string // Query with flags removed
+	Flags []Flag // Flags in the order they appear in the query
+}
+
+// Flag is a word in a query that starts with one of the recognised
+// prefixes, e.g. "#work" is Flag{Prefix: "#", Value: "work"}.
+type Flag struct {
+	Prefix string
+	Value  string // Rest of the word; may be empty
+}
+
+// Has returns true if query contains a flag with the given prefix.
+func (q Query) Has(prefix string) bool {
+	for _, f := range q.Flags {
+		if f.Prefix == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// Values returns the values of all flags with the given prefix.
+func (q Query) Values(prefix string) []string {
+	var values []string
+	for _, f := range q.Flags {
+		if f.Prefix == prefix {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// ParseQuery splits query on whitespace and extracts the words that
+// start with one of prefixes as Flags. The remaining words are joined
+// with single spaces to form Query.Text. If prefixes overlap,
+// e.g. ">" and ">>", the longest matching prefix wins.
+func ParseQuery(query string, prefixes ...string) Query {
+	// check longest prefixes first
+	prefixes = append([]string{}, prefixes...)
+	sort.SliceStable(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	var (
+		q     Query
+		words []string
+	)
+outer:
+	for _, word := range strings.Fields(query) {
+		for _, p := range prefixes {
+			if p != "" && strings.HasPrefix(word, p) {
+				q.Flags = append(q.Flags, Flag{Prefix: p, Value: word[len(p):]})
+				continue outer
+			}
+		}
+		words = append(words, word)
+	}
+	q.Text = strings.Join(words, " ")
+	return q
+}
+
+// ParseQuery parses the workflow's arguments with ParseQuery(), using the
+// prefixes set with the QueryFlags Option. As it calls Args(), magic
+// arguments are handled first.
+func (wf *Workflow) ParseQuery() Query {
+	return ParseQuery(strings.Join(wf.Args(), " "), wf.queryFlags...)
+}
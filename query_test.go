@@ -0,0 +1,61 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Parallel()
+
+	prefixes := []string{"#", ">", ">>"}
+	tests := []struct {
+		in    string
+		text  string
+		flags []Flag
+	}{
+		{"", "", nil},
+		{"  search  terms ", "search terms", nil},
+		{"search #work", "search", []Flag{{"#", "work"}}},
+		{"#work search #home terms", "search terms", []Flag{{"#", "work"}, {"#", "home"}}},
+		{">> search >", "search", []Flag{{">>", ""}, {">", ""}}},
+		{"a#b", "a#b", nil},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.in, func(t *testing.T) {
+			t.Parallel()
+			q := ParseQuery(td.in, prefixes...)
+			assert.Equal(t, td.text, q.Text, "unexpected text")
+			assert.Equal(t, td.flags, q.Flags, "unexpected flags")
+		})
+	}
+
+	q := ParseQuery("search #work #home >")
+	assert.Equal(t, "search #work #home >", q.Text, "flags parsed without prefixes")
+
+	q = ParseQuery("search #work #home >", prefixes...)
+	assert.True(t, q.Has("#"), "flag # not found")
+	assert.True(t, q.Has(">"), "flag > not found")
+	assert.False(t, q.Has(">>"), "unexpected flag >>")
+	assert.Equal(t, []string{"work", "home"}, q.Values("#"), "unexpected values")
+}
+
+func TestWorkflow_ParseQuery(t *testing.T) {
+	withTestWf(func(wf *Workflow) {
+		args := os.Args
+		defer func() { os.Args = args }()
+		os.Args = []string{"workflow", "search #work", "terms"}
+
+		wf.Configure(QueryFlags("#"))
+		q := wf.ParseQuery()
+		assert.Equal(t, "search terms", q.Text, "unexpected text")
+		assert.Equal(t, []string{"work"}, q.Values("#"), "unexpected flags")
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/deanishe/awgo/util"
+)
+
+// stderrTailSize is the maximum number of bytes of STDERR output
+// included in a ScriptError.
+const stderrTailSize = 1024
+
+// ScriptError is returned by Workflow.RunScript if a script fails or
+// times out.
+type ScriptError struct {
+	Cmd      []string // Command and its arguments
+	ExitCode int      // Exit status, or -1 if the script didn't exit
+	Stderr   string   // End of the script's STDERR output
+	Err      error    // Underlying error
+}
+
+// Error implements error.
+func (err *ScriptError) Error() string {
+	msg := fmt.Sprintf("script %v failed (exit %d): %v", err.Cmd, err.ExitCode, err.Err)
+	if err.Stderr != "" {
+		msg += ": " + err.Stderr
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error, e.g. context.DeadlineExceeded if
+// the script timed out.
+func (err *ScriptError) Unwrap() error { return err.Err }
+
+// RunScript runs the script or executable at path with the workflow's
+// environment and returns its STDOUT output. Script files that aren't
+// executable are run with the interpreter for their extension (see
+// util.DefaultInterpreters). Other commands are looked up in PATH.
+//
+// The script is killed if it runs for longer than timeout. A timeout of
+// 0 means no timeout.
+//
+// If the script fails or times out, the returned error is a *ScriptError
+// containing the exit status and the end of the script's STDERR output.
+func (wf *Workflow) RunScript(timeout time.Duration, path string, args ...string) ([]byte, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	name, argv := path, args
+	if util.PathExists(path) {
+		if c := (util.Runners{util.Executable, util.Script}).Cmd(path, args...); c != nil {
+			name, argv = c.Path, c.Args[1:]
+		}
+	}
+
+	var (
+		stdout, stderr bytes.Buffer
+		cmd            = exec.CommandContext(ctx, name, argv...)
+	)
+	cmd.Env = wf.scriptEnv()
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	wf.debugf("running script %v (timeout=%v)", cmd.Args, timeout)
+	if err := cmd.Run(); err != nil {
+		serr := &ScriptError{
+			Cmd:      cmd.Args,
+			ExitCode: -1,
+			Stderr:   tail(stderr.String(), stderrTailSize),
+			Err:      err,
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			serr.Err = ctx.Err()
+		} else if ee, ok := err.(*exec.ExitError); ok {
+			serr.ExitCode = ee.ExitCode()
+		}
+		return nil, serr
+	}
+	return stdout.Bytes(), nil
+}
+
+// scriptEnv returns the environment for RunScript: the process
+// environment plus the workflow's configuration.
+func (wf *Workflow) scriptEnv() []string {
+	env := os.Environ()
+	for _, k := range wf.Config.keys() {
+		env = append(env, k+"="+wf.Config.Get(k))
+	}
+	return env
+}
+
+// tail returns the last n bytes of s without leading or trailing whitespace.
+func tail(s string, n int) string {
+	if len(s) > n {
+		s = s[len(s)-n:]
+	}
+	return strings.TrimSpace(s)
+}
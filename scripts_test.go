@@ -0,0 +1,76 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Scripts are run with the workflow's environment
+func TestWorkflow_RunScript(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		data, err := wf.RunScript(0, "/bin/sh", "-c", "echo $alfred_workflow_bundleid; echo err >&2")
+		require.Nil(t, err, "run script failed")
+		assert.Equal(t, wf.BundleID()+"\n", string(data), "unexpected output")
+
+		// script file run with interpreter
+		p := filepath.Join(wf.DataDir(), "script.sh")
+		require.Nil(t, ioutil.WriteFile(p, []byte(`echo "hello $1"`), 0600), "write script failed")
+		data, err = wf.RunScript(time.Second, p, "world")
+		require.Nil(t, err, "run script file failed")
+		assert.Equal(t, "hello world\n", string(data), "unexpected output")
+	})
+}
+
+// Failing scripts return a ScriptError
+func TestWorkflow_RunScript_error(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		_, err := wf.RunScript(0, "/bin/sh", "-c", "echo out; echo oops >&2; exit 3")
+		require.NotNil(t, err, "failing script succeeded")
+
+		var serr *ScriptError
+		require.True(t, errors.As(err, &serr), "not a ScriptError")
+		assert.Equal(t, 3, serr.ExitCode, "unexpected exit code")
+		assert.Equal(t, "oops", serr.Stderr, "unexpected stderr")
+		assert.Equal(t, "/bin/sh", serr.Cmd[0], "unexpected command")
+
+		// timeout
+		start := time.Now()
+		_, err = wf.RunScript(50*time.Millisecond, "/bin/sleep", "5")
+		require.NotNil(t, err, "script did not time out")
+		assert.True(t, time.Since(start) < 2*time.Second, "script not killed")
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "not a timeout error")
+		require.True(t, errors.As(err, &serr), "not a ScriptError")
+		assert.Equal(t, -1, serr.ExitCode, "unexpected exit code")
+	})
+}
+
+func TestTail(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in string
+		n  int
+		x  string
+	}{
+		{"", 10, ""},
+		{"short\n", 10, "short"},
+		{"line 1\nline 2\n", 7, "line 2"},
+	}
+	for _, td := range tests {
+		assert.Equal(t, td.x, tail(td.in, td.n), "unexpected tail of %q", td.in)
+	}
+}
@@ -5,9 +5,10 @@
 Package update implements an API for fetching workflow updates from remote servers.
 
 It is the "backend" for aw.Workflow's update API, and provides concrete updaters for
-GitHub and Gitea releases, and Alfred metadata.json files (as aw.Options). Updater
-implements aw.Updater and you can create a custom Updater to use with
-aw.Workflow/aw.Update() by passing a custom implementation of Source to NewUpdater().
+GitHub, GitLab and Gitea releases, Alfred metadata.json files, JSON
+manifests and local directories (as aw.Options). Updater implements aw.Updater and you can create
+a custom Updater to use with aw.Workflow/aw.Update() by passing a custom
+implementation of Source to NewUpdater().
 
 The only hard requirement is support for (mostly) semantic version numbers. See
 SemVer documentation and http://semver.org for details.
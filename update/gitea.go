@@ -13,8 +13,9 @@ import (
 
 // Gitea is a Workflow Option. It sets a Workflow Updater for the specified Gitea repo.
 // Repo name should be the URL of the repo, e.g. "git.deanishe.net/deanishe/alfred-ssh".
-func Gitea(repo string) aw.Option {
-	return newOption(&source{URL: giteaURL(repo), fetch: getURL})
+// Options configure the Updater.
+func Gitea(repo string, opts ...Option) aw.Option {
+	return newOption(&source{URL: giteaURL(repo)}, opts...)
 }
 
 func giteaURL(repo string) string {
@@ -4,10 +4,13 @@
 package update
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -21,41 +24,79 @@ var rxWorkflowFile = regexp.MustCompile(`\.alfred(\d+)?workflow$`)
 
 // GitHub is a Workflow Option. It sets a Workflow Updater for the specified GitHub repo.
 // Repo name should be of the form "username/repo", e.g. "deanishe/alfred-ssh".
-func GitHub(repo string) aw.Option {
+// Options configure the Updater.
+func GitHub(repo string, opts ...Option) aw.Option {
 	return newOption(&source{
-		URL:   "https://api.github.com/repos/" + repo + "/releases",
-		fetch: getURL,
-	})
+		URL: "https://api.github.com/repos/" + repo + "/releases",
+	}, opts...)
 }
 
 // create new Updater option from Source.
-func newOption(src Source) aw.Option {
+func newOption(src Source, opts ...Option) aw.Option {
 	return func(wf *aw.Workflow) aw.Option {
-		u, _ := NewUpdater(src, wf.Version(), filepath.Join(wf.CacheDir(), "_aw/update"))
+		u, _ := NewUpdater(src, wf.Version(), filepath.Join(wf.CacheDir(), "_aw/update"), opts...)
 		return aw.Update(u)(wf)
 	}
 }
 
 type source struct {
-	URL   string
-	dls   []Download
-	fetch func(URL string) ([]byte, error)
+	URL    string
+	dls    []Download
+	header http.Header                         // sent with HTTP requests
+	client *httpClient                         // set by Updater
+	fetch  func(URL string) ([]byte, error)    // defaults to client.get
+	parse  func(js []byte) ([]Download, error) // defaults to parseReleases
+}
+
+// setClient implements httpSource.
+func (src *source) setClient(c *httpClient) { src.client = c }
+
+// downloadHeader implements headerSource. The header is only sent to the
+// same host as the releases API, so credentials aren't leaked to hosts
+// that release assets are linked to.
+func (src *source) downloadHeader(URL string) http.Header {
+	if src.header == nil {
+		return nil
+	}
+	a, err := url.Parse(src.URL)
+	if err != nil {
+		return nil
+	}
+	b, err := url.Parse(URL)
+	if err != nil || a.Host != b.Host {
+		return nil
+	}
+	return src.header
 }
 
 // Downloads implements Source.
 func (src *source) Downloads() ([]Download, error) {
+	return src.downloadsContext(context.Background())
+}
+
+// downloadsContext implements contextSource.
+func (src *source) downloadsContext(ctx context.Context) ([]Download, error) {
 	if src.dls != nil {
 		return src.dls, nil
 	}
 
-	src.dls = []Download{}
-	js, err := src.fetch(src.URL)
+	fetch := src.fetch
+	if fetch == nil {
+		fetch = func(URL string) ([]byte, error) { return src.client.get(ctx, URL, src.header) }
+	}
+	js, err := fetch(src.URL)
 	if err != nil {
 		return nil, err
 	}
-	if src.dls, err = parseReleases(js); err != nil {
+	parse := src.parse
+	if parse == nil {
+		parse = parseReleases
+	}
+	dls, err := parse(js)
+	if err != nil {
 		return nil, err
 	}
+	src.dls = dls
 
 	return src.dls, nil
 }
@@ -84,10 +84,22 @@ func testParseReleases(name, jsonPath string, downloads []Download, t *testing.T
 			},
 		}
 		dls, err := src.Downloads()
-		require.Nil(t, err, "parse empty JSON")
+		require.Nil(t, err, "parse empty releases failed")
 		require.Equal(t, 0, len(dls), "downloads in empty JSON")
 	})
 
+	t.Run(name+" parse releases without workflows", func(t *testing.T) {
+		t.Parallel()
+		src := &source{
+			fetch: func(URL string) ([]byte, error) {
+				return []byte(`[{"tag_name": "v1.0", "assets": [{"name": "Dummy.zip"}]}]`), nil
+			},
+		}
+		dls, err := src.Downloads()
+		require.Nil(t, err, "parse releases without workflows failed")
+		require.Equal(t, 0, len(dls), "downloads in JSON without workflows")
+	})
+
 	t.Run(name+" parse releases", func(t *testing.T) {
 		t.Parallel()
 		src := &source{
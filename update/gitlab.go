@@ -0,0 +1,140 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	aw "github.com/deanishe/awgo"
+)
+
+// EnvVarGitLabToken is the workflow variable GitLab reads a personal
+// access token from. The token is only required for private repos.
+const EnvVarGitLabToken = "GITLAB_TOKEN"
+
+// GitLab is a Workflow Option. It sets a Workflow Updater for the specified GitLab repo.
+// Repo name should be of the form "username/repo", e.g. "deanishe/alfred-ssh",
+// which is looked up on gitlab.com. To use a self-hosted GitLab instance,
+// prefix the repo with its hostname, e.g. "gitlab.example.com/deanishe/alfred-ssh".
+// As usernames may contain dots, a repo name with only two parts, e.g.
+// "john.doe/alfred-ssh", is always looked up on gitlab.com.
+//
+// Workflow files must be attached to releases as (asset) links.
+//
+// To check for updates in a private repo, set the workflow variable
+// GITLAB_TOKEN to a personal access token with (at least) read_api scope.
+// The token is also sent when downloading release assets from the GitLab
+// host, but not to other hosts that assets link to.
+//
+// Options configure the Updater.
+func GitLab(repo string, opts ...Option) aw.Option {
+	return func(wf *aw.Workflow) aw.Option {
+		src := &source{
+			URL:   gitlabURL(repo),
+			parse: parseGitLabReleases,
+		}
+		if token := wf.Config.Get(EnvVarGitLabToken); token != "" {
+			src.header = http.Header{}
+			src.header.Set("PRIVATE-TOKEN", token)
+		}
+		return newOption(src, opts...)(wf)
+	}
+}
+
+// gitlabURL returns the URL of repo's releases API endpoint.
+func gitlabURL(repo string) string {
+	var (
+		scheme = "https"
+		host   = "gitlab.com"
+	)
+	repo = strings.TrimSpace(repo)
+	if repo == "" {
+		return ""
+	}
+	if u, err := url.Parse(repo); err == nil && u.Scheme != "" && u.Host != "" {
+		scheme, host, repo = u.Scheme, u.Host, u.Path
+	}
+	path := strings.Split(strings.Trim(repo, "/"), "/")
+	// hostname of a self-hosted instance
+	if len(path) > 2 && strings.Contains(path[0], ".") {
+		host, path = path[0], path[1:]
+	}
+	// GitLab supports nested groups, so path may be longer than 2
+	if len(path) < 2 {
+		return ""
+	}
+	for _, s := range path {
+		if s == "" {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("%s://%s/api/v4/projects/%s/releases",
+		scheme, host, url.PathEscape(strings.Join(path, "/")))
+}
+
+// parse GitLab releases JSON.
+func parseGitLabReleases(js []byte) ([]Download, error) {
+	var (
+		dls  = []Download{}
+		rels = []struct {
+			Name     string `json:"name"`
+			Tag      string `json:"tag_name"`
+			Upcoming bool   `json:"upcoming_release"`
+			Assets   struct {
+				Links []struct {
+					Name      string `json:"name"`
+					URL       string `json:"url"`
+					DirectURL string `json:"direct_asset_url"`
+				} `json:"links"`
+			} `json:"assets"`
+		}{}
+	)
+
+	if err := json.Unmarshal(js, &rels); err != nil {
+		return nil, err
+	}
+	for _, r := range rels {
+		if len(r.Assets.Links) == 0 {
+			continue
+		}
+		v, err := NewSemVer(r.Tag)
+		if err != nil {
+			log.Printf("ignored release %s: not semantic: %v", r.Tag, err)
+			continue
+		}
+		var all []Download
+		for _, l := range r.Assets.Links {
+			m := rxWorkflowFile.FindStringSubmatch(l.Name)
+			if len(m) != 2 {
+				log.Printf("ignored release %s: no workflow files", r.Tag)
+				continue
+			}
+			URL := l.DirectURL
+			if URL == "" {
+				URL = l.URL
+			}
+			// GitLab has no pre-release flag, so use the version number
+			all = append(all, Download{
+				URL:        URL,
+				Filename:   l.Name,
+				Version:    v,
+				Prerelease: r.Upcoming || v.Prerelease != "",
+			})
+		}
+		if err := isValidRelease(all); err != nil {
+			log.Printf("ignored release %s: %v", r.Tag, err)
+			continue
+		}
+		dls = append(dls, all...)
+	}
+	sort.Sort(sort.Reverse(byVersion(dls)))
+	return dls, nil
+}
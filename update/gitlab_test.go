@@ -0,0 +1,199 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	aw "github.com/deanishe/awgo"
+)
+
+// 6 valid releases, including one prerelease
+// v1.0, v2.0, v6.0, v7.1.0-beta, v9.0 (Alfred 4+ only), v10.0-beta
+var testGitLabDownloads = []Download{
+	// Latest version for Alfred 4
+	{
+		URL:        "https://gitlab.com/deanishe/alfred-workflow-dummy/-/releases/v10.0-beta/downloads/Dummy-10.0-beta.alfredworkflow",
+		Filename:   "Dummy-10.0-beta.alfredworkflow",
+		Version:    mustVersion("v10.0-beta"),
+		Prerelease: true,
+	},
+	// Latest stable version for Alfred 4
+	{
+		URL:        "https://gitlab.com/deanishe/alfred-workflow-dummy/-/releases/v9.0/downloads/Dummy-9.0.alfred4workflow",
+		Filename:   "Dummy-9.0.alfred4workflow",
+		Version:    mustVersion("v9.0"),
+		Prerelease: false,
+	},
+	// Latest version for Alfred 3
+	{
+		URL:        "https://gitlab.com/deanishe/alfred-workflow-dummy/-/releases/v7.1.0-beta/downloads/Dummy-7.1-beta.alfredworkflow",
+		Filename:   "Dummy-7.1-beta.alfredworkflow",
+		Version:    mustVersion("v7.1.0-beta"),
+		Prerelease: true,
+	},
+	// Latest stable version for Alfred 3
+	{
+		URL:        "https://gitlab.com/deanishe/alfred-workflow-dummy/-/releases/v6.0/downloads/Dummy-6.0.alfred4workflow",
+		Filename:   "Dummy-6.0.alfred4workflow",
+		Version:    mustVersion("v6.0"),
+		Prerelease: false,
+	},
+	{
+		URL:        "https://gitlab.com/deanishe/alfred-workflow-dummy/-/releases/v6.0/downloads/Dummy-6.0.alfred3workflow",
+		Filename:   "Dummy-6.0.alfred3workflow",
+		Version:    mustVersion("v6.0"),
+		Prerelease: false,
+	},
+	{
+		URL:        "https://gitlab.com/deanishe/alfred-workflow-dummy/-/releases/v6.0/downloads/Dummy-6.0.alfredworkflow",
+		Filename:   "Dummy-6.0.alfredworkflow",
+		Version:    mustVersion("v6.0"),
+		Prerelease: false,
+	},
+	{
+		URL:        "https://gitlab.com/deanishe/alfred-workflow-dummy/-/releases/v2.0/downloads/Dummy-2.0.alfredworkflow",
+		Filename:   "Dummy-2.0.alfredworkflow",
+		Version:    mustVersion("v2.0"),
+		Prerelease: false,
+	},
+	{
+		URL:        "https://gitlab.com/deanishe/alfred-workflow-dummy/-/releases/v1.0/downloads/Dummy-1.0.alfredworkflow",
+		Filename:   "Dummy-1.0.alfredworkflow",
+		Version:    mustVersion("v1.0"),
+		Prerelease: false,
+	},
+}
+
+func TestParseGitLab(t *testing.T) {
+	t.Parallel()
+
+	dls, err := parseGitLabReleases(mustRead("testdata/gitlab-releases.json"))
+	require.Nil(t, err, "parse GitLab JSON failed")
+	assert.Equal(t, testGitLabDownloads, dls, "unexpected downloads")
+
+	dls, err = parseGitLabReleases(mustRead("testdata/empty.json"))
+	require.Nil(t, err, "parse empty JSON failed")
+	assert.Equal(t, 0, len(dls), "downloads in empty JSON")
+}
+
+func TestGitLabURL(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		repo string
+		url  string
+	}{
+		// Invalid input
+		{"", ""},
+		{"deanishe", ""},
+		{"https://gitlab.example.com/deanishe", ""},
+		// Valid URLs
+		{"deanishe/nonexistent", "https://gitlab.com/api/v4/projects/deanishe%2Fnonexistent/releases"},
+		{"deanishe/group/nonexistent", "https://gitlab.com/api/v4/projects/deanishe%2Fgroup%2Fnonexistent/releases"},
+		// dotted namespaces, e.g. usernames, are only hostnames if followed by a full path
+		{"john.doe/project", "https://gitlab.com/api/v4/projects/john.doe%2Fproject/releases"},
+		{"gitlab.example.com/deanishe", "https://gitlab.com/api/v4/projects/gitlab.example.com%2Fdeanishe/releases"},
+		{"gitlab.example.com/deanishe/nonexistent", "https://gitlab.example.com/api/v4/projects/deanishe%2Fnonexistent/releases"},
+		{"https://gitlab.example.com/deanishe/nonexistent", "https://gitlab.example.com/api/v4/projects/deanishe%2Fnonexistent/releases"},
+		{"http://gitlab.example.com/deanishe/nonexistent", "http://gitlab.example.com/api/v4/projects/deanishe%2Fnonexistent/releases"},
+	}
+
+	for _, td := range data {
+		td := td
+		t.Run(td.repo, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.url, gitlabURL(td.repo), "unexpected URL")
+		})
+	}
+}
+
+func TestGitLabUpdater(t *testing.T) {
+	t.Parallel()
+	src := &source{
+		URL: "https://gitlab.com/api/v4/projects/deanishe%2Falfred-workflow-dummy/releases",
+		fetch: func(URL string) ([]byte, error) {
+			return ioutil.ReadFile("testdata/gitlab-releases.json")
+		},
+		parse: parseGitLabReleases,
+	}
+
+	testSourceUpdater("GitLab", src, t)
+}
+
+type testEnv map[string]string
+
+func (e testEnv) Lookup(key string) (string, bool) {
+	v, ok := e[key]
+	return v, ok
+}
+
+// Token is sent in PRIVATE-TOKEN header.
+func TestGitLabToken(t *testing.T) {
+	t.Parallel()
+
+	var token string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("PRIVATE-TOKEN")
+		if _, err := w.Write(mustRead("testdata/gitlab-releases.json")); err != nil {
+			panic(err)
+		}
+	}))
+	defer ts.Close()
+
+	withTempDir(func(dir string) {
+		e := testEnv{
+			"alfred_workflow_bundleid": "net.deanishe.awgo",
+			"alfred_workflow_cache":    dir,
+			"alfred_workflow_data":     dir,
+			"alfred_workflow_version":  "0.1",
+			EnvVarGitLabToken:          "secret",
+		}
+		wf := aw.NewFromEnv(e, GitLab(ts.URL+"/deanishe/alfred-workflow-dummy"))
+		require.Nil(t, wf.CheckForUpdate(), "check for update failed")
+		assert.Equal(t, "secret", token, "unexpected token")
+	})
+}
+
+// Token is only sent with downloads from the GitLab host.
+func TestGitLabDownloadHeader(t *testing.T) {
+	t.Parallel()
+
+	src := &source{
+		URL:    "https://gitlab.example.com/api/v4/projects/deanishe%2Fdummy/releases",
+		header: http.Header{"Private-Token": []string{"secret"}},
+	}
+	tests := []struct {
+		URL   string
+		token string
+	}{
+		{"https://gitlab.example.com/deanishe/dummy/uploads/1/Dummy.alfredworkflow", "secret"},
+		{"https://downloads.example.com/Dummy.alfredworkflow", ""},
+	}
+	for _, td := range tests {
+		td := td
+		t.Run(td.URL, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.token, src.downloadHeader(td.URL).Get("PRIVATE-TOKEN"), "unexpected token")
+		})
+	}
+	assert.Nil(t, (&source{URL: src.URL}).downloadHeader(src.URL), "unexpected header")
+}
+
+// Configure Workflow to update from a GitLab repo.
+func ExampleGitLab() {
+	// Set source repo using GitLab Option
+	wf := aw.New(GitLab("deanishe/alfred-ssh"))
+	// Is a check for a newer version due?
+	fmt.Println(wf.UpdateCheckDue())
+	// Output:
+	// true
+}
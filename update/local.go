@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	aw "github.com/deanishe/awgo"
+)
+
+// extract version from a filename like "Workflow-1.2.0.alfredworkflow".
+// The version must follow a separator, so digits in the workflow's name,
+// e.g. "Workflow2-1.2.0.alfredworkflow", aren't part of it.
+var rxFilenameVersion = regexp.MustCompile(`(?:^|[-_ ])v?(\d+(?:\.\d+){0,2}(?:-[0-9A-Za-z.]+)?)\.alfred\d*workflow$`)
+
+// Local is a Workflow Option. It sets a Workflow Updater based on the
+// workflow files in a local directory, e.g. a mounted network share, so
+// workflows can be updated without access to the Internet.
+//
+// The version of each file is read from its name, which must end with
+// a separator ("-", "_" or a space), a semantic version and the workflow
+// extension, e.g.
+// "Workflow-1.2.0.alfredworkflow" or "Workflow-v2.0.0-beta.alfred4workflow".
+// Files whose versions have a pre-release part are treated as pre-releases.
+// Other files are ignored, so the directory should only contain releases
+// of one workflow.
+//
+// Options configure the Updater.
+func Local(dir string, opts ...Option) aw.Option {
+	return newOption(&localSource{dir: dir}, opts...)
+}
+
+// localSource is a Source that reads workflow files from a directory.
+type localSource struct {
+	dir string
+}
+
+// Downloads implements Source.
+func (src *localSource) Downloads() ([]Download, error) {
+	dir, err := filepath.Abs(src.dir)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read releases directory: %w", err)
+	}
+
+	dls := []Download{}
+	for _, fi := range infos {
+		if fi.IsDir() || !rxWorkflowFile.MatchString(fi.Name()) {
+			continue
+		}
+		dl, err := localDownload(dir, fi.Name())
+		if err != nil {
+			log.Printf("ignored file %q: %v", fi.Name(), err)
+			continue
+		}
+		dls = append(dls, dl)
+	}
+	sort.Sort(sort.Reverse(byVersion(dls)))
+	return dls, nil
+}
+
+// create Download from workflow file in dir.
+func localDownload(dir, name string) (Download, error) {
+	var dl Download
+	m := rxFilenameVersion.FindStringSubmatch(name)
+	if len(m) != 2 {
+		return dl, errors.New("no version in filename")
+	}
+	v, err := NewSemVer(m[1])
+	if err != nil {
+		return dl, fmt.Errorf("not semantic: %w", err)
+	}
+	return Download{
+		URL:        "file://" + filepath.Join(dir, name),
+		Filename:   name,
+		Version:    v,
+		Prerelease: v.Prerelease != "",
+	}, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalSource(t *testing.T) {
+	t.Parallel()
+
+	withTempDir(func(dir string) {
+		dir, err := filepath.EvalSymlinks(dir)
+		require.Nil(t, err, "resolve temp dir failed")
+
+		src := &localSource{dir: dir}
+		dls, err := src.Downloads()
+		require.Nil(t, err, "read empty directory failed")
+		assert.Equal(t, 0, len(dls), "downloads in empty directory")
+
+		for _, name := range []string{
+			"Dummy-1.0.alfredworkflow",
+			"Dummy-v2.0.0.alfredworkflow",
+			"Dummy-3.0-beta.1.alfred4workflow",
+			"Dummy.alfredworkflow",
+			"README.txt",
+		} {
+			require.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0600), "write file failed")
+		}
+		require.Nil(t, os.Mkdir(filepath.Join(dir, "Dummy-9.0.alfredworkflow"), 0700), "create directory failed")
+
+		x := []Download{
+			{
+				URL:        "file://" + filepath.Join(dir, "Dummy-3.0-beta.1.alfred4workflow"),
+				Filename:   "Dummy-3.0-beta.1.alfred4workflow",
+				Version:    mustVersion("v3.0-beta.1"),
+				Prerelease: true,
+			},
+			{
+				URL:      "file://" + filepath.Join(dir, "Dummy-v2.0.0.alfredworkflow"),
+				Filename: "Dummy-v2.0.0.alfredworkflow",
+				Version:  mustVersion("v2.0"),
+			},
+			{
+				URL:      "file://" + filepath.Join(dir, "Dummy-1.0.alfredworkflow"),
+				Filename: "Dummy-1.0.alfredworkflow",
+				Version:  mustVersion("v1.0"),
+			},
+		}
+		dls, err = src.Downloads()
+		require.Nil(t, err, "read downloads failed")
+		assert.Equal(t, x, dls, "unexpected downloads")
+	})
+
+	_, err := (&localSource{dir: "testdata/missing"}).Downloads()
+	assert.True(t, errors.Is(err, os.ErrNotExist), "unexpected error: %v", err)
+}
+
+func TestLocalDownload(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		v    string // expected version; empty if filename is invalid
+	}{
+		{"Dummy-1.0.alfredworkflow", "1.0.0"},
+		{"Dummy_v2.0.0.alfred4workflow", "2.0.0"},
+		{"Dummy 3.0-beta.1.alfredworkflow", "3.0.0-beta.1"},
+		{"1.0.alfredworkflow", "1.0.0"},
+		// digits in workflow name aren't part of version
+		{"MyWorkflow2-1.0.0.alfredworkflow", "1.0.0"},
+		{"Alfred3-Helper-1.2.0.alfredworkflow", "1.2.0"},
+		// no version
+		{"Dummy.alfredworkflow", ""},
+		{"Dummy2.alfredworkflow", ""},
+		{"Dummy-v.alfredworkflow", ""},
+	}
+	for _, td := range tests {
+		td := td
+		t.Run(td.name, func(t *testing.T) {
+			t.Parallel()
+			dl, err := localDownload("/releases", td.name)
+			if td.v == "" {
+				assert.NotNil(t, err, "accepted invalid filename")
+				return
+			}
+			require.Nil(t, err, "parse filename failed")
+			assert.Equal(t, td.v, dl.Version.String(), "unexpected version")
+			assert.Equal(t, "file:///releases/"+td.name, dl.URL, "unexpected URL")
+		})
+	}
+}
+
+func TestLocalUpdater(t *testing.T) {
+	origRun := runCommand
+	defer func() { runCommand = origRun }()
+	me := &mockExec{}
+	runCommand = me.Run
+
+	withTempDir(func(dir string) {
+		var (
+			releases = filepath.Join(dir, "releases")
+			cache    = filepath.Join(dir, "cache")
+			name     = "Dummy-2.0.alfredworkflow"
+		)
+		require.Nil(t, os.Mkdir(releases, 0700), "create directory failed")
+		require.Nil(t, ioutil.WriteFile(filepath.Join(releases, name), []byte("dummy 2.0"), 0600), "write file failed")
+
+		u, err := NewUpdater(&localSource{dir: releases}, "1.0", cache)
+		require.Nil(t, err, "create updater failed")
+		require.Nil(t, u.CheckForUpdate(), "check for update failed")
+		assert.True(t, u.UpdateAvailable(), "no update available")
+
+		require.Nil(t, u.Install(), "install failed")
+		p := filepath.Join(cache, name)
+		assert.Equal(t, []string{"open", p}, me.args, "unexpected command")
+		data, err := ioutil.ReadFile(p)
+		require.Nil(t, err, "read workflow file failed")
+		assert.Equal(t, "dummy 2.0", string(data), "unexpected file contents")
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	aw "github.com/deanishe/awgo"
+)
+
+// Manifest is a Workflow Option. It sets a Workflow Updater based on
+// a JSON manifest file hosted on your own server.
+//
+// URL is the location of the manifest, which must contain an array of
+// releases:
+//
+//	[
+//	  {
+//	    "version": "1.1.0",
+//	    "url": "https://example.com/Workflow-1.1.0.alfredworkflow",
+//	    "checksum": "<hex-encoded SHA-256 of workflow file>"
+//	  },
+//	  {
+//	    "version": "1.0.0",
+//	    "url": "https://example.com/Workflow-1.0.0.alfredworkflow"
+//	  }
+//	]
+//
+// Releases whose URLs don't point to a workflow file or whose versions
+// aren't semantic are ignored. Versions with a pre-release part, e.g.
+// "1.2.0-beta", are treated as pre-releases. If checksum is set,
+// Install() rejects a downloaded file that doesn't match it.
+//
+// Options configure the Updater.
+func Manifest(url string, opts ...Option) aw.Option {
+	return newOption(&source{URL: url, parse: parseManifest}, opts...)
+}
+
+// data model for manifest JSON.
+type manifestRelease struct {
+	Version  string `json:"version"`
+	URL      string `json:"url"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// parse manifest JSON.
+func parseManifest(js []byte) ([]Download, error) {
+	var (
+		dls  = []Download{}
+		rels = []manifestRelease{}
+	)
+	if err := json.Unmarshal(js, &rels); err != nil {
+		return nil, err
+	}
+	for _, r := range rels {
+		dl, err := manifestDownload(r)
+		if err != nil {
+			log.Printf("ignored release %q: %v", r.Version, err)
+			continue
+		}
+		dls = append(dls, dl)
+	}
+	sort.Sort(sort.Reverse(byVersion(dls)))
+	return dls, nil
+}
+
+// convert manifest release to Download.
+func manifestDownload(r manifestRelease) (Download, error) {
+	var dl Download
+	v, err := NewSemVer(r.Version)
+	if err != nil {
+		return dl, fmt.Errorf("not semantic: %w", err)
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return dl, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return dl, fmt.Errorf("invalid scheme: %s", u.Scheme)
+	}
+	dl = Download{
+		URL:        r.URL,
+		Filename:   filepath.Base(u.Path),
+		Version:    v,
+		Prerelease: v.Prerelease != "",
+		Checksum:   strings.ToLower(r.Checksum),
+	}
+	if m := rxWorkflowFile.FindStringSubmatch(dl.Filename); len(m) != 2 {
+		return dl, fmt.Errorf("not a workflow file: %s", dl.Filename)
+	}
+	return dl, nil
+}
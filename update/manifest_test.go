@@ -0,0 +1,118 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence applies http://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	aw "github.com/deanishe/awgo"
+)
+
+// SHA-256 of "dummy 2.0"
+const testChecksum = "9361db12aa80e2bf476b22e9dbec0444345369c2d3b2caab38f010649b0b50cd"
+
+// 3 valid releases, including one prerelease
+var testManifestDownloads = []Download{
+	{
+		URL:        "https://example.com/workflows/Dummy-3.0-beta.alfredworkflow",
+		Filename:   "Dummy-3.0-beta.alfredworkflow",
+		Version:    mustVersion("v3.0-beta"),
+		Prerelease: true,
+	},
+	{
+		URL:        "https://example.com/workflows/Dummy-2.0.alfredworkflow",
+		Filename:   "Dummy-2.0.alfredworkflow",
+		Version:    mustVersion("v2.0"),
+		Prerelease: false,
+		Checksum:   testChecksum,
+	},
+	{
+		URL:        "https://example.com/workflows/Dummy-1.0.alfredworkflow",
+		Filename:   "Dummy-1.0.alfredworkflow",
+		Version:    mustVersion("v1.0"),
+		Prerelease: false,
+	},
+}
+
+func TestParseManifest(t *testing.T) {
+	t.Parallel()
+
+	dls, err := parseManifest(mustRead("testdata/manifest.json"))
+	require.Nil(t, err, "parse manifest failed")
+	assert.Equal(t, testManifestDownloads, dls, "unexpected downloads")
+
+	dls, err = parseManifest(mustRead("testdata/empty.json"))
+	require.Nil(t, err, "parse empty manifest failed")
+	assert.Equal(t, 0, len(dls), "downloads in empty manifest")
+
+	dls, err = parseManifest([]byte(`[{"version": "1.0", "url": "https://example.com/Dummy.zip"}]`))
+	require.Nil(t, err, "parse manifest without workflows failed")
+	assert.Equal(t, 0, len(dls), "downloads in manifest without workflows")
+
+	_, err = parseManifest(mustRead("testdata/invalid.json"))
+	assert.NotNil(t, err, "parsed invalid JSON")
+}
+
+func TestManifestUpdater(t *testing.T) {
+	origRun := runCommand
+	origDownload := download
+	defer func() {
+		runCommand = origRun
+		download = origDownload
+	}()
+
+	me := &mockExec{}
+	runCommand = me.Run
+	var contents string
+	download = func(_ context.Context, c *httpClient, URL, path string, _ http.Header) error {
+		return ioutil.WriteFile(path, []byte(contents), 0600)
+	}
+
+	src := &source{
+		URL: "https://example.com/workflows/manifest.json",
+		fetch: func(URL string) ([]byte, error) {
+			return ioutil.ReadFile("testdata/manifest.json")
+		},
+		parse: parseManifest,
+	}
+
+	withTempDir(func(dir string) {
+		u, err := NewUpdater(src, "1.0", dir)
+		require.Nil(t, err, "create updater failed")
+		require.Nil(t, u.CheckForUpdate(), "check for update failed")
+		assert.True(t, u.UpdateAvailable(), "no update available")
+
+		// checksum doesn't match
+		contents = "corrupt"
+		err = u.Install()
+		assert.True(t, errors.Is(err, ErrChecksumMismatch), "unexpected error: %v", err)
+		_, err = ioutil.ReadFile(filepath.Join(dir, "Dummy-2.0.alfredworkflow"))
+		assert.NotNil(t, err, "corrupt file not deleted")
+		assert.Equal(t, "", me.name, "corrupt file installed")
+
+		// checksum matches
+		contents = "dummy 2.0"
+		require.Nil(t, u.Install(), "install failed")
+		assert.Equal(t, []string{"open", filepath.Join(dir, "Dummy-2.0.alfredworkflow")}, me.args, "unexpected command")
+	})
+}
+
+// Configure Workflow to update from a JSON manifest on your own server.
+func ExampleManifest() {
+	// Set manifest URL using Manifest Option
+	wf := aw.New(Manifest("https://example.com/workflows/manifest.json"))
+	// Is a check for a newer version due?
+	fmt.Println(wf.UpdateCheckDue())
+	// Output:
+	// true
+}
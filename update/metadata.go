@@ -4,6 +4,7 @@
 package update
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,31 +20,48 @@ import (
 // URL is the location of the `metadata.json` file. Note: You *must*
 // set `downloadurl` in the `metadata.json` file to the URL
 // of your .alfredworkflow (or .alfred4workflow etc.) file.
-func Metadata(url string) aw.Option {
+//
+// Options configure the Updater.
+func Metadata(url string, opts ...Option) aw.Option {
 	return func(wf *aw.Workflow) aw.Option {
-		u, _ := NewUpdater(&metadataSource{url: url, fetch: getURL},
+		src := &metadataSource{url: url}
+		u, _ := NewUpdater(src,
 			wf.Version(),
 			filepath.Join(wf.CacheDir(), "_aw/update"),
+			opts...,
 		)
 		return aw.Update(u)(wf)
 	}
 }
 
 type metadataSource struct {
-	url   string
-	dl    *Download
-	fetch func(URL string) ([]byte, error)
+	url    string
+	dl     *Download
+	client *httpClient                      // set by Updater
+	fetch  func(URL string) ([]byte, error) // defaults to client.get
 }
 
+// setClient implements httpSource.
+func (src *metadataSource) setClient(c *httpClient) { src.client = c }
+
 // Downloads implements Source.
 func (src *metadataSource) Downloads() ([]Download, error) {
+	return src.downloadsContext(context.Background())
+}
+
+// downloadsContext implements contextSource.
+func (src *metadataSource) downloadsContext(ctx context.Context) ([]Download, error) {
 	if src.dl == nil {
 		var (
 			js  []byte
 			dl  Download
 			err error
 		)
-		if js, err = src.fetch(src.url); err != nil {
+		fetch := src.fetch
+		if fetch == nil {
+			fetch = func(URL string) ([]byte, error) { return src.client.get(ctx, URL, nil) }
+		}
+		if js, err = fetch(src.url); err != nil {
 			return nil, err
 		}
 		if dl, err = parseMetadata(js); err != nil {
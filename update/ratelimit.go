@@ -0,0 +1,134 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deanishe/awgo/util"
+)
+
+// RateLimitFile is where Updaters with a rate limit (see UpdateRateLimit)
+// keep track of their HTTP requests. All Updaters that use the same file
+// share the same limit, so by default, all AwGo workflows run by a user
+// share a single limit.
+var RateLimitFile = defaultRateLimitFile()
+
+// ErrRateLimited is returned by Updater if a request is refused because it
+// would exceed the limit set with UpdateRateLimit.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// UpdateRateLimit is an Option that limits the Updater to n HTTP requests
+// (including retries and downloads) per period. The limit is stored in
+// RateLimitFile and shared between processes, so it also applies across
+// runs of the workflow and across workflows. Requests that would exceed
+// the limit fail with ErrRateLimited.
+//
+// For example, UpdateRateLimit(10, time.Hour) keeps workflows well below
+// GitHub's limit of 60 unauthenticated API requests per hour.
+func UpdateRateLimit(n int, per time.Duration) Option {
+	return func(u *Updater) {
+		u.client.limiter = &rateLimiter{path: RateLimitFile, n: n, per: per}
+	}
+}
+
+// defaultRateLimitFile returns a path in the user's cache directory.
+func defaultRateLimitFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "net.deanishe.awgo", "update-rate-limit.json")
+}
+
+// rateLimiter is a token bucket persisted to a file. The bucket holds up
+// to n tokens and is refilled at a rate of n per period. Each request
+// takes one token.
+type rateLimiter struct {
+	path string
+	n    int
+	per  time.Duration
+}
+
+// bucket is the persisted state of a rateLimiter.
+type bucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// take removes a token from the bucket. It returns an error wrapping
+// ErrRateLimited if the bucket is empty. Errors reading or writing the
+// bucket are logged, and the request is allowed.
+func (rl *rateLimiter) take() error {
+	if rl == nil || rl.n <= 0 || rl.per <= 0 {
+		return nil
+	}
+	wait, err := rl.reserve(time.Now())
+	if err != nil {
+		log.Printf("[warning] rate limiter: %v", err)
+		return nil
+	}
+	if wait > 0 {
+		return fmt.Errorf("%w: try again in %v", ErrRateLimited, wait.Round(time.Second))
+	}
+	return nil
+}
+
+// reserve takes a token if one is available. Otherwise, it returns how long
+// until the next token is available.
+func (rl *rateLimiter) reserve(now time.Time) (time.Duration, error) {
+	util.MustExist(filepath.Dir(rl.path))
+	f, err := util.LockFile(rl.path, true)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close() // also releases lock
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return 0, err
+	}
+	b := bucket{Tokens: float64(rl.n), Updated: now}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &b); err != nil {
+			log.Printf("[warning] rate limiter: invalid state in %q: %v", rl.path, err)
+			b = bucket{Tokens: float64(rl.n), Updated: now}
+		}
+	}
+
+	// refill bucket
+	rate := float64(rl.n) / rl.per.Seconds() // tokens per second
+	if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens += elapsed * rate
+	}
+	if b.Tokens > float64(rl.n) {
+		b.Tokens = float64(rl.n)
+	}
+	b.Updated = now
+
+	var wait time.Duration
+	if b.Tokens >= 1 {
+		b.Tokens--
+	} else {
+		wait = time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+	}
+
+	if data, err = json.Marshal(b); err != nil {
+		return 0, err
+	}
+	if err := f.Truncate(0); err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return 0, err
+	}
+	return wait, nil
+}
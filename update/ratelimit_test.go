@@ -0,0 +1,82 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package update
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Token bucket is emptied and refilled
+func TestRateLimiter_reserve(t *testing.T) {
+	t.Parallel()
+
+	withTempDir(func(dir string) {
+		var (
+			rl  = &rateLimiter{path: filepath.Join(dir, "limit.json"), n: 2, per: time.Hour}
+			now = time.Now()
+		)
+		tests := []struct {
+			t time.Time
+			x time.Duration
+		}{
+			{now, 0},
+			{now, 0},
+			{now, 30 * time.Minute},
+			{now.Add(15 * time.Minute), 15 * time.Minute},
+			{now.Add(30 * time.Minute), 0},
+			{now.Add(30 * time.Minute), 30 * time.Minute},
+			// bucket doesn't hold more than n tokens
+			{now.Add(10 * time.Hour), 0},
+			{now.Add(10 * time.Hour), 0},
+			{now.Add(10 * time.Hour), 30 * time.Minute},
+		}
+		for i, td := range tests {
+			wait, err := rl.reserve(td.t)
+			require.Nil(t, err, "reserve failed")
+			assert.Equal(t, td.x, wait.Round(time.Second), "unexpected wait for request #%d", i)
+		}
+	})
+}
+
+// Updaters share rate limit
+func TestUpdateRateLimit(t *testing.T) {
+	t.Parallel()
+
+	var n int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		http.ServeFile(w, r, "testdata/github-releases.json")
+	}))
+	defer ts.Close()
+
+	withTempDir(func(limitDir string) {
+		newUpdater := func(dir string) *Updater {
+			u, err := NewUpdater(&source{URL: ts.URL}, "0.1", dir, UpdateRateLimit(2, time.Hour))
+			require.Nil(t, err, "create updater failed")
+			u.client.limiter.path = filepath.Join(limitDir, "limit.json")
+			return u
+		}
+
+		// separate cache directories, i.e. different workflows
+		for i := 0; i < 3; i++ {
+			withTempDir(func(dir string) {
+				err := newUpdater(dir).CheckForUpdate()
+				if i < 2 {
+					require.Nil(t, err, "check for update failed")
+				} else {
+					assert.True(t, errors.Is(err, ErrRateLimited), "request not rate limited")
+				}
+			})
+		}
+		assert.Equal(t, 2, n, "unexpected number of requests")
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/deanishe/awgo/internal/semver"
 )
 
 // SemVers implements sort.Interface for SemVer.
@@ -33,9 +35,11 @@ func SortSemVer(versions []SemVer) {
 //	- Minor and patch versions are not required, e.g. "v1" and "v1.0" are valid.
 //	- Version string may be prefixed with "v", e.g. "v1" or "v3.0.1-beta".
 //	  The "v" prefix is stripped, so "v1" == "1.0.0".
-//	- Dots and integers are ignored in pre-release identifiers: they are
-//	  compared purely alphanumerically, e.g. "v1-beta.11" < "v1-beta.2".
-//	  Use "v1-beta.02" instead.
+//
+// Pre-release identifiers are compared as the standard specifies: they are
+// split on dots and numeric identifiers are compared as integers, so
+// "v1-beta.2" < "v1-beta.11". Earlier versions compared pre-release strings
+// purely alphanumerically, which sorted "v1-beta.11" before "v1-beta.2".
 type SemVer struct {
 	Major      uint64 // Increment for breaking changes.
 	Minor      uint64 // Increment for added/deprecated functionality.
@@ -121,40 +125,17 @@ func (v SemVer) String() string {
 //	 0 if v == v2
 //	 1 if v > v2
 func (v SemVer) Compare(v2 SemVer) int {
-	if v.Major != v2.Major {
-		if v.Major > v2.Major {
-			return 1
-		}
-		return -1
-	}
-	if v.Minor != v2.Minor {
-		if v.Minor > v2.Minor {
-			return 1
-		}
-		return -1
-	}
-	if v.Patch != v2.Patch {
-		if v.Patch > v2.Patch {
-			return 1
-		}
-		return -1
-	}
-
-	// Check if one version is prerelease and the other isn't
-	if v.Prerelease == "" && v2.Prerelease != "" {
-		return 1
-	} else if v.Prerelease != "" && v2.Prerelease == "" {
-		return -1
-	}
+	return v.version().Compare(v2.version())
+}
 
-	if v.Prerelease > v2.Prerelease {
-		return 1
-	} else if v.Prerelease < v2.Prerelease {
-		return -1
+// version returns the parts of SemVer that determine its precedence.
+func (v SemVer) version() semver.Version {
+	return semver.Version{
+		Major:      v.Major,
+		Minor:      v.Minor,
+		Patch:      v.Patch,
+		Prerelease: v.Prerelease,
 	}
-
-	// Semver ignores build info
-	return 0
 }
 
 // Eq checks if v == v2
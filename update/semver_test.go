@@ -93,6 +93,7 @@ func TestSemVer_Compare(t *testing.T) {
 		{"1.1.0-beta", "1.1.0-alpha", 1},
 		{"1.1.0-alpha", "1.1.0-alpha", 0},
 		{"1.1.0-rc1", "1.1.0-rc2", -1},
+		{"1.1.0-beta.2", "1.1.0-beta.11", -1},
 		{"10.1.0", "1.1.0", 1},
 		{"0.4.5", "0.5.0-beta", -1},
 		// Build metadata ignored
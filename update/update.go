@@ -4,6 +4,9 @@
 package update
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,6 +19,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/deanishe/awgo/util"
@@ -26,24 +30,58 @@ var (
 	UpdateInterval = 24 * time.Hour
 	// HTTPTimeout is the timeout for establishing an HTTP(S) connection.
 	HTTPTimeout = 60 * time.Second
+	// ProgressInterval is the minimum interval between calls to the
+	// function set with UpdateProgress.
+	ProgressInterval = 200 * time.Millisecond
 
-	// HTTP client used to talk to APIs
-	client *http.Client
+	// Delay before retrying a failed HTTP request. Doubled after each retry.
+	retryDelay = time.Second
+
+	// HTTP client used by Sources not attached to an Updater
+	defaultClient = &httpClient{}
+)
+
+// Errors returned by Updater. Use errors.Is() to check for them.
+//
+// Sources don't return ErrNoReleases or ErrNoMatchingAsset: a repo without
+// (usable) releases is not an error, so they return an empty list instead.
+var (
+	// ErrNoReleases is returned by Install() if the source has no
+	// releases with a workflow file.
+	ErrNoReleases = errors.New("no releases found")
+	// ErrNoMatchingAsset is returned by Install() if no workflow file
+	// is compatible with the installed version of Alfred, or all
+	// releases are pre-releases and Prereleases is false.
+	ErrNoMatchingAsset = errors.New("no downloads available")
+	// ErrChecksumMismatch is returned by Install() if a downloaded
+	// workflow file doesn't match its Download's Checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
 )
 
+// DownloadError is returned when a server responds to a request with
+// an HTTP error status. Use errors.As() to retrieve it.
+type DownloadError struct {
+	URL        string // URL that was requested
+	StatusCode int    // HTTP status code, e.g. 404
+	Status     string // HTTP status, e.g. "404 Not Found"
+}
+
+// Error implements error.
+func (err *DownloadError) Error() string { return err.Status }
+
 // Mockable functions
 var (
 	// Run command
 	runCommand = func(name string, arg ...string) error {
 		return exec.Command(name, arg...).Run()
 	}
-	// save a URL to a filepath.
-	download = func(URL, path string) error {
-		res, err := openURL(URL)
+	// save a URL to a filepath. Header may be nil.
+	download = func(ctx context.Context, c *httpClient, URL, path string, header http.Header) error {
+		body, size, err := openDownload(ctx, c, URL, header)
 		if err != nil {
 			return err
 		}
-		defer res.Body.Close()
+		defer body.Close()
 
 		util.MustExist(filepath.Dir(path))
 		out, err := os.Create(path)
@@ -51,7 +89,13 @@ var (
 			return err
 		}
 		defer out.Close()
-		n, err := io.Copy(out, res.Body)
+		var w io.Writer = out
+		if c != nil && c.progress != nil {
+			pw := &progressWriter{fn: c.progress, total: size}
+			defer pw.report()
+			w = io.MultiWriter(out, pw)
+		}
+		n, err := io.Copy(w, body)
 		if err != nil {
 			return err
 		}
@@ -60,6 +104,28 @@ var (
 	}
 )
 
+// openDownload opens URL for reading and returns its size (-1 if unknown).
+// file:// URLs are read from the local filesystem. Header may be nil.
+func openDownload(ctx context.Context, c *httpClient, URL string, header http.Header) (io.ReadCloser, int64, error) {
+	if strings.HasPrefix(URL, "file://") {
+		f, err := os.Open(strings.TrimPrefix(URL, "file://"))
+		if err != nil {
+			return nil, 0, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, fi.Size(), nil
+	}
+	res, err := c.open(ctx, URL, header)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.Body, res.ContentLength, nil
+}
+
 // Source provides workflow files that can be downloaded.
 // This is what concrete updaters (e.g. GitHub, Gitea) should implement.
 // Source is called by the Updater after every updater interval.
@@ -68,6 +134,75 @@ type Source interface {
 	Downloads() ([]Download, error)
 }
 
+// httpSource is a Source that fetches data with its Updater's HTTP client.
+type httpSource interface {
+	setClient(c *httpClient)
+}
+
+// contextSource is a Source whose requests can be cancelled.
+type contextSource interface {
+	downloadsContext(ctx context.Context) ([]Download, error)
+}
+
+// headerSource is a Source that requires HTTP headers, e.g. for
+// authentication, to download its workflow files.
+type headerSource interface {
+	downloadHeader(URL string) http.Header
+}
+
+// Option configures an Updater. Options may be passed to NewUpdater()
+// or to the Workflow Options of the built-in sources, e.g. GitHub().
+type Option func(u *Updater)
+
+// UpdateRetries is an Option that sets how many times the Updater retries
+// an HTTP request that failed with a transient error, i.e. a timeout or
+// a server (5xx) error. It waits 1s before the first retry and doubles
+// the delay after each subsequent failure. The default is 0 (no retries).
+func UpdateRetries(n int) Option {
+	return func(u *Updater) { u.client.retries = n }
+}
+
+// UpdateTimeout is an Option that sets the timeout for the Updater's
+// HTTP requests, including downloading the workflow file. By default,
+// only establishing a connection times out (after HTTPTimeout).
+func UpdateTimeout(d time.Duration) Option {
+	return func(u *Updater) { u.client.timeout = d }
+}
+
+// UpdateHTTPClient is an Option that sets the HTTP client the Updater
+// uses for all its requests, i.e. fetching releases and downloading the
+// workflow file, e.g. to use a proxy. If UpdateTimeout is also set, it
+// overrides the client's Timeout. By default, the Updater uses a client
+// with HTTPTimeout as its connection timeout.
+func UpdateHTTPClient(c *http.Client) Option {
+	return func(u *Updater) { u.client.client = c }
+}
+
+// UpdateProgress is an Option that sets a function that's called with the
+// progress of the workflow download during Install, e.g. to show it in
+// a notification. written is the number of bytes downloaded so far, and
+// total is the size of the file or -1 if it's unknown. fn is called at
+// most every ProgressInterval and once more when the download finishes.
+func UpdateProgress(fn func(written, total int64)) Option {
+	return func(u *Updater) { u.client.progress = fn }
+}
+
+// UpdatePrereleases is an Option that sets whether the Updater considers
+// pre-releases, e.g. "v1.2.0-beta.1", when looking for a newer version.
+// By default, pre-releases are ignored. Versions are compared according
+// to SemVer, so 1.2.0-beta.1 is older than 1.2.0.
+func UpdatePrereleases(on bool) Option {
+	return func(u *Updater) { u.Prereleases = on }
+}
+
+// UpdateCheckInterval is an Option that sets how often the Updater checks
+// for a new version. Until the interval has elapsed since the last check,
+// CheckDue returns false and UpdateAvailable only reads cached data.
+// The default is UpdateInterval.
+func UpdateCheckInterval(d time.Duration) Option {
+	return func(u *Updater) { u.updateInterval = d }
+}
+
 // byVersion sorts downloads by version.
 type byVersion []Download
 
@@ -85,13 +220,16 @@ func (s byVersion) Less(i, j int) bool {
 // Download is an Alfred workflow available for download & installation.
 // It is the primary update data structure, returned by all Sources.
 type Download struct {
-	URL string // Where the workflow file can be downloaded from
+	URL string // Where the workflow file can be downloaded from (http, https or file)
 	// Filename for downloaded file.
 	// Must have extension .alfredworkflow or .alfredXworkflow where X is a number,
 	// otherwise the Download will be ignored.
 	Filename   string
 	Version    SemVer // Semantic version no.
 	Prerelease bool   // Whether this version is a pre-release
+	// Hex-encoded SHA-256 hash of the workflow file. If set, Install()
+	// verifies the downloaded file against it.
+	Checksum string `json:",omitempty"`
 }
 
 // AlfredVersion returns minimum compatible version of Alfred based on file extension.
@@ -145,12 +283,17 @@ type Updater struct {
 	cacheDir      string // Directory to store cache files in
 	pathLastCheck string // Cache path for check time
 	pathDownloads string // Cache path for available downloads
+
+	client *httpClient // Retrieves releases and workflow files
 }
 
 // NewUpdater creates a new Updater for Source. `currentVersion` is the workflow's
 // version number and `cacheDir` is a directory where the Updater can cache
 // a list of available releases.
-func NewUpdater(src Source, currentVersion, cacheDir string) (*Updater, error) {
+//
+// Options are applied in order, and the Updater's HTTP settings are also
+// used by the built-in sources (GitHub, Gitea, etc.).
+func NewUpdater(src Source, currentVersion, cacheDir string, opts ...Option) (*Updater, error) {
 	v, err := NewSemVer(currentVersion)
 	if err != nil {
 		return nil, fmt.Errorf("invalid version %q: %w", currentVersion, err)
@@ -167,6 +310,13 @@ func NewUpdater(src Source, currentVersion, cacheDir string) (*Updater, error) {
 		updateInterval: UpdateInterval,
 		pathLastCheck:  filepath.Join(cacheDir, "LastCheckTime.txt"),
 		pathDownloads:  filepath.Join(cacheDir, "Downloads.json"),
+		client:         &httpClient{},
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	if s, ok := src.(httpSource); ok {
+		s.setClient(u.client)
 	}
 
 	if s := os.Getenv("alfred_version"); s != "" {
@@ -213,7 +363,18 @@ func (u *Updater) CheckDue() bool {
 
 // CheckForUpdate fetches the list of releases from remote (via Releaser)
 // and caches it locally.
+//
+// A source without any releases is not an error: CheckForUpdate caches the
+// empty list and returns nil, and UpdateAvailable reports false. Unlike
+// Install, it never returns ErrNoReleases, so a workflow with no releases
+// yet doesn't show an error on every check.
 func (u *Updater) CheckForUpdate() error {
+	return u.CheckForUpdateContext(context.Background())
+}
+
+// CheckForUpdateContext is like CheckForUpdate, but the HTTP requests of
+// the built-in sources are cancelled when ctx is done.
+func (u *Updater) CheckForUpdateContext(ctx context.Context) error {
 	// If update fails, don't try again for at least an hour
 	u.LastCheck = time.Now().Add(-u.updateInterval).Add(time.Hour)
 	defer u.cacheLastCheck()
@@ -224,7 +385,12 @@ func (u *Updater) CheckForUpdate() error {
 		err  error
 	)
 
-	if dls, err = u.Source.Downloads(); err != nil {
+	if s, ok := u.Source.(contextSource); ok {
+		dls, err = s.downloadsContext(ctx)
+	} else {
+		dls, err = u.Source.Downloads()
+	}
+	if err != nil {
 		return err
 	}
 	u.downloads = dls
@@ -244,19 +410,58 @@ func (u *Updater) CheckForUpdate() error {
 // After the workflow file is downloaded, Install calls Alfred to
 // install the update.
 func (u *Updater) Install() error {
+	return u.InstallContext(context.Background())
+}
+
+// InstallContext is like Install, but the download is cancelled when
+// ctx is done.
+func (u *Updater) InstallContext(ctx context.Context) error {
 	dl := u.latest()
 	if dl == nil {
-		return errors.New("no downloads available")
+		if len(u.downloads) == 0 {
+			return ErrNoReleases
+		}
+		return ErrNoMatchingAsset
 	}
 	log.Printf("downloading version %s ...", dl.Version)
 	p := filepath.Join(u.cacheDir, dl.Filename)
-	if err := download(dl.URL, p); err != nil {
+	var header http.Header
+	if s, ok := u.Source.(headerSource); ok {
+		header = s.downloadHeader(dl.URL)
+	}
+	if err := download(ctx, u.client, dl.URL, p, header); err != nil {
 		return err
 	}
+	if dl.Checksum != "" {
+		if err := verifyChecksum(p, dl.Checksum); err != nil {
+			if err := os.Remove(p); err != nil {
+				log.Printf("error: delete %q: %v", p, err)
+			}
+			return err
+		}
+	}
 
 	return runCommand("open", p)
 }
 
+// verifyChecksum checks that the SHA-256 hash of the file at path is checksum.
+func verifyChecksum(path, checksum string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if s := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(s, checksum) {
+		return fmt.Errorf("%w: %s: expected %s, got %s", ErrChecksumMismatch, filepath.Base(path), checksum, s)
+	}
+	return nil
+}
+
 // clearCache removes the update cache.
 func (u *Updater) clearCache() {
 	if err := util.ClearDirectory(u.cacheDir); err != nil {
@@ -338,9 +543,25 @@ func makeHTTPClient() *http.Client {
 	}
 }
 
-// getURL returns the contents of a URL.
-func getURL(url string) ([]byte, error) {
-	res, err := openURL(url)
+// httpClient performs the HTTP requests of an Updater and its Source.
+// The zero value uses a client created by makeHTTPClient and doesn't
+// retry failed requests. A nil *httpClient fetches with defaultClient.
+type httpClient struct {
+	client  *http.Client  // Underlying client
+	retries int           // How often to retry transient failures
+	timeout time.Duration // Timeout for requests; 0 = no timeout
+	// Called with progress of downloads
+	progress func(written, total int64)
+	limiter  *rateLimiter // Limits number of requests; may be nil
+}
+
+// get returns the contents of a URL. Header may be nil.
+// If c is nil, defaultClient is used.
+func (c *httpClient) get(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	if c == nil {
+		c = defaultClient
+	}
+	res, err := c.open(ctx, url, header)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -348,21 +569,94 @@ func getURL(url string) ([]byte, error) {
 	return ioutil.ReadAll(res.Body)
 }
 
-// openURL returns an http.Response. It will return an error if the
-// HTTP status code > 299.
-func openURL(url string) (*http.Response, error) {
+// open returns an http.Response. It will return an error if the
+// HTTP status code > 299. Header may be nil. Requests that fail with
+// a transient error are retried until ctx is done.
+func (c *httpClient) open(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	if c == nil {
+		c = defaultClient
+	}
+	delay := retryDelay
+	for i := 0; ; i++ {
+		r, err := c.do(ctx, url, header)
+		if err == nil || i >= c.retries || !isTransient(err) {
+			return r, err
+		}
+		log.Printf("[warning] fetch %s failed (%v), retrying in %v ...", url, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// do performs a single GET request.
+func (c *httpClient) do(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	if err := c.limiter.take(); err != nil {
+		return nil, err
+	}
 	log.Printf("fetching %s ...", url)
-	if client == nil {
-		client = makeHTTPClient()
+	if c.client == nil {
+		c.client = makeHTTPClient()
+	}
+	client := c.client
+	if c.timeout > 0 {
+		cl := *client
+		cl.Timeout = c.timeout
+		client = &cl
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
 	}
-	r, err := client.Get(url)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	r, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	log.Printf("[%d] %s", r.StatusCode, url)
 	if r.StatusCode > 299 {
 		r.Body.Close()
-		return nil, errors.New(r.Status)
+		return nil, &DownloadError{URL: url, StatusCode: r.StatusCode, Status: r.Status}
 	}
 	return r, nil
 }
+
+// progressWriter counts bytes written to it and passes the count to fn
+// at most every ProgressInterval.
+type progressWriter struct {
+	fn         func(written, total int64)
+	total      int64
+	written    int64
+	lastReport time.Time
+}
+
+// Write implements io.Writer.
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if time.Since(w.lastReport) >= ProgressInterval {
+		w.report()
+	}
+	return len(p), nil
+}
+
+// report calls fn with the current progress.
+func (w *progressWriter) report() {
+	w.lastReport = time.Now()
+	w.fn(w.written, w.total)
+}
+
+// isTransient returns true if err is a timeout or server error,
+// i.e. the request may succeed if retried.
+func isTransient(err error) bool {
+	var dlErr *DownloadError
+	if errors.As(err, &dlErr) {
+		return dlErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -4,12 +4,14 @@
 package update
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -57,7 +59,7 @@ func withTempDir(fn func(dir string)) {
 	if err != nil {
 		panic(err)
 	}
-	defer panicOnError(os.RemoveAll(dir))
+	defer func() { panicOnError(os.RemoveAll(dir)) }()
 	fn(dir)
 }
 
@@ -176,14 +178,14 @@ func TestUpdater_Install(t *testing.T) {
 
 	me := &mockExec{}
 	runCommand = me.Run
-	download = func(URL, path string) error { return nil }
+	download = func(_ context.Context, c *httpClient, URL, path string, _ http.Header) error { return nil }
 
 	withTempDir(func(dir string) {
 		u, err := NewUpdater(testSrc1, "0.2.2", dir)
 		require.Nil(t, err, "create updater failed")
 
 		assert.False(t, u.UpdateAvailable(), "empty updater has update")
-		assert.NotNil(t, u.Install(), "empty updater installed")
+		assert.Equal(t, ErrNoReleases, u.Install(), "empty updater installed")
 		assert.Nil(t, u.CheckForUpdate(), "get releases failed")
 		assert.Nil(t, u.Install(), "install failed")
 		assert.Equal(t, "open", me.name, "wrong command called")
@@ -203,8 +205,8 @@ func TestHTTPClient(t *testing.T) {
 		}))
 		defer ts.Close()
 
-		data, err := getURL(ts.URL)
-		require.Nil(t, err, "getURL failed")
+		data, err := defaultClient.get(context.Background(), ts.URL, nil)
+		require.Nil(t, err, "get URL failed")
 		ts.Close()
 
 		assert.Equal(t, "hello\n", string(data), "unexpected response")
@@ -218,8 +220,12 @@ func TestHTTPClient(t *testing.T) {
 		}))
 		defer ts.Close()
 
-		_, err := getURL(ts.URL)
+		_, err := defaultClient.get(context.Background(), ts.URL, nil)
 		assert.NotNil(t, err, "404 request succeeded")
+		var dlErr *DownloadError
+		require.True(t, errors.As(err, &dlErr), "not a DownloadError: %v", err)
+		assert.Equal(t, http.StatusNotFound, dlErr.StatusCode, "unexpected status code")
+		assert.Equal(t, "404 Not Found", err.Error(), "unexpected error message")
 		ts.Close()
 	})
 
@@ -232,7 +238,7 @@ func TestHTTPClient(t *testing.T) {
 		URL := ts.URL
 		ts.Close()
 
-		_, err := getURL(URL)
+		_, err := defaultClient.get(context.Background(), URL, nil)
 		assert.NotNil(t, err, "bad request succeeded")
 		ts.Close()
 	})
@@ -251,7 +257,7 @@ func TestHTTPClient(t *testing.T) {
 		require.Nil(t, err, "create tempfile failed")
 		defer panicOnError(f.Close())
 
-		err = download(ts.URL, f.Name())
+		err = download(context.Background(), defaultClient, ts.URL, f.Name(), nil)
 		require.Nil(t, err, "download failed")
 
 		data, err := ioutil.ReadFile(f.Name())
@@ -270,11 +276,180 @@ func TestHTTPClient(t *testing.T) {
 		URL := ts.URL
 		ts.Close()
 
-		err := download(URL, "")
+		err := download(context.Background(), defaultClient, URL, "", nil)
 		require.NotNil(t, err, "bad download succeeded")
 	})
 }
 
+// Transient errors are retried, others aren't.
+func TestHTTPClient_Retry(t *testing.T) {
+	origDelay := retryDelay
+	retryDelay = time.Millisecond
+	defer func() { retryDelay = origDelay }()
+
+	// handler fails with status until it has been called n times
+	failing := func(n, status int, count *int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			*count++
+			if *count <= n {
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			if _, err := fmt.Fprintln(w, "hello"); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		retries   int
+		failures  int
+		status    int
+		fail      bool
+		callCount int
+	}{
+		{"no retries", 0, 2, http.StatusServiceUnavailable, true, 1},
+		{"succeed after retries", 3, 2, http.StatusServiceUnavailable, false, 3},
+		{"retries exhausted", 1, 2, http.StatusInternalServerError, true, 2},
+		{"404 not retried", 3, 2, http.StatusNotFound, true, 1},
+	}
+
+	for _, td := range tests {
+		var count int
+		ts := httptest.NewServer(failing(td.failures, td.status, &count))
+		c := &httpClient{retries: td.retries}
+		data, err := c.get(context.Background(), ts.URL, nil)
+		ts.Close()
+
+		if td.fail {
+			assert.NotNil(t, err, "%s: request succeeded", td.name)
+		} else {
+			assert.Nil(t, err, "%s: request failed", td.name)
+			assert.Equal(t, "hello\n", string(data), "%s: unexpected response", td.name)
+		}
+		assert.Equal(t, td.callCount, count, "%s: unexpected number of requests", td.name)
+	}
+}
+
+func TestHTTPClient_Timeout(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 200)
+	}))
+	defer ts.Close()
+
+	c := &httpClient{timeout: time.Millisecond * 20}
+	_, err := c.get(context.Background(), ts.URL, nil)
+	require.NotNil(t, err, "request didn't time out")
+	assert.True(t, isTransient(err), "timeout is not transient")
+}
+
+// countingTransport counts the requests made through it.
+type countingTransport struct {
+	n int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.n++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestUpdateHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/github-releases.json")
+	}))
+	defer ts.Close()
+
+	withTempDir(func(dir string) {
+		tr := &countingTransport{}
+		src := &source{URL: ts.URL}
+		u, err := NewUpdater(src, "0.1", dir, UpdateHTTPClient(&http.Client{Transport: tr}))
+		require.Nil(t, err, "create updater failed")
+
+		require.Nil(t, u.CheckForUpdate(), "check for update failed")
+		assert.Equal(t, 1, tr.n, "releases not fetched with custom client")
+
+		require.Nil(t, download(context.Background(), u.client, ts.URL, filepath.Join(dir, "test.alfredworkflow"), nil), "download failed")
+		assert.Equal(t, 2, tr.n, "file not downloaded with custom client")
+	})
+}
+
+func TestUpdateProgress(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("dummy workflow data")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	withTempDir(func(dir string) {
+		var calls, written, total int64
+		fn := func(w, t int64) {
+			calls++
+			written, total = w, t
+		}
+		u, err := NewUpdater(&source{}, "0.1", dir, UpdateProgress(fn))
+		require.Nil(t, err, "create updater failed")
+
+		require.Nil(t, download(context.Background(), u.client, ts.URL, filepath.Join(dir, "test.alfredworkflow"), nil), "download failed")
+		assert.True(t, calls > 0, "progress function not called")
+		assert.Equal(t, int64(len(data)), written, "unexpected bytes written")
+		assert.Equal(t, int64(len(data)), total, "unexpected total")
+	})
+}
+
+// Requests are cancelled with their context
+func TestUpdater_context(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	withTempDir(func(dir string) {
+		u, err := NewUpdater(&source{URL: ts.URL}, "0.1", dir, UpdateRetries(3))
+		require.Nil(t, err, "create updater failed")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err = u.CheckForUpdateContext(ctx)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "request not cancelled: %v", err)
+		assert.True(t, time.Since(start) < 2*time.Second, "request not cancelled in time")
+
+		u.downloads = []Download{{URL: ts.URL, Filename: "test.alfredworkflow", Version: mustVersion("0.2")}}
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		err = u.InstallContext(ctx)
+		assert.True(t, errors.Is(err, context.Canceled), "download not cancelled: %v", err)
+	})
+}
+
+func TestUpdaterOptions(t *testing.T) {
+	t.Parallel()
+
+	withTempDir(func(dir string) {
+		src := &source{}
+		u, err := NewUpdater(src, "0.1", dir, UpdateRetries(2), UpdateTimeout(time.Second),
+			UpdateCheckInterval(time.Hour), UpdatePrereleases(true))
+		require.Nil(t, err, "create updater failed")
+		assert.Equal(t, 2, u.client.retries, "unexpected retries")
+		assert.Equal(t, time.Second, u.client.timeout, "unexpected timeout")
+		assert.Equal(t, time.Hour, u.updateInterval, "unexpected update interval")
+		assert.True(t, u.Prereleases, "pre-releases not enabled")
+		assert.Equal(t, u.client, src.client, "source doesn't use Updater's client")
+	})
+}
+
 func TestRunCommand(t *testing.T) {
 	assert.Nil(t, runCommand("/usr/bin/true"), `exec "/usr/bin/true" returned error`)
 }
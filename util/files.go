@@ -4,12 +4,14 @@
 package util
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"syscall"
 )
 
 // MustExist creates all specified directories and returns the last one.
@@ -51,6 +53,33 @@ func ClearDirectory(p string) error {
 	return err
 }
 
+// ErrLocked is returned by LockFile if another process holds the lock
+// and LockFile was told not to wait.
+var ErrLocked = errors.New("file is locked")
+
+// LockFile opens the file at path, creating it if necessary, and acquires
+// an exclusive advisory lock on it. If wait is true, LockFile blocks until
+// the lock is available, otherwise it returns ErrLocked if the file is
+// already locked. Close the returned file to release the lock.
+func LockFile(path string, wait bool) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open lockfile: %w", err)
+	}
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("lock %q: %w", path, err)
+	}
+	return f, nil
+}
+
 // WriteFile is an atomic version of ioutil.WriteFile.
 // It first writes data to a temporary file and renames this to
 // filename if the write is successful.
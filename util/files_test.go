@@ -152,3 +152,20 @@ func TestWriteFile(t *testing.T) {
 	})
 	require.Nil(t, err, "inTempDir failed")
 }
+
+func TestLockFile(t *testing.T) {
+	err := inTempDir(func(dir string) {
+		name := "test.lock"
+		f, err := LockFile(name, false)
+		require.Nil(t, err, "LockFile failed")
+
+		_, err = LockFile(name, false)
+		assert.Equal(t, ErrLocked, err, "locked file locked again")
+
+		require.Nil(t, f.Close(), "close lockfile failed")
+		f, err = LockFile(name, false)
+		require.Nil(t, err, "LockFile failed after unlock")
+		require.Nil(t, f.Close(), "close lockfile failed")
+	})
+	require.Nil(t, err, "inTempDir failed")
+}
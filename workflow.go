@@ -11,8 +11,10 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"go.deanishe.net/fuzzy"
 
@@ -119,7 +121,10 @@ type Workflow struct {
 	maxLogSize  int            // Maximum size of log file in bytes
 	magicPrefix string         // Overrides DefaultMagicPrefix for magic actions.
 	maxResults  int            // max. results to send to Alfred. 0 means send all.
+	emptyTitle  string         // Title of warning sent if there are no items
+	emptySub    string         // Subtitle of warning sent if there are no items
 	sortOptions []fuzzy.Option // Options for fuzzy filtering
+	queryFlags  []string       // Prefixes of flags recognised by ParseQuery
 	textErrors  bool           // Show errors as plaintext, not Alfred JSON
 	helpURL     string         // URL to help page (shown if there's an error)
 	dir         string         // Directory workflow is in
@@ -127,6 +132,10 @@ type Workflow struct {
 	dataDir     string         // Workflow's data directory
 	sessionName string         // Name of the variable sessionID is stored in
 	sessionID   string         // Random session ID
+	newSession  *bool          // Cached result of IsNewSession
+	info        *Info          // Parsed info.plist
+	prevVersion *string        // Cached result of PreviousVersion
+	exitHooks   []func()       // Functions registered with OnExit
 
 	execFunc commandRunner // Run external commands
 }
@@ -185,6 +194,7 @@ func NewFromEnv(env Env, opts ...Option) *Workflow {
 		dataMA{wf},
 		clearDataMA{wf},
 		resetMA{wf},
+		envMA{wf},
 	))
 
 	wf.Configure(opts...)
@@ -270,8 +280,17 @@ func (wf *Workflow) BundleID() string {
 func (wf *Workflow) Name() string { return wf.Config.Get(EnvVarName) }
 
 // Version returns the workflow's version set in the workflow's configuration
-// sheet in Alfred Preferences.
-func (wf *Workflow) Version() string { return wf.Config.Get(EnvVarVersion) }
+// sheet in Alfred Preferences. If the version isn't set in the environment,
+// it is read from the workflow's info.plist (see Info()).
+func (wf *Workflow) Version() string {
+	if v := wf.Config.Get(EnvVarVersion); v != "" {
+		return v
+	}
+	if info, err := wf.Info(); err == nil {
+		return info.Version
+	}
+	return ""
+}
 
 // SessionID returns the session ID for this run of the workflow.
 // This is used internally for session-scoped caching.
@@ -294,18 +313,73 @@ func (wf *Workflow) SessionID() string {
 	return wf.sessionID
 }
 
+// IsNewSession returns true if this is the first run of the workflow in
+// the current session, i.e. the user has just opened Alfred or switched
+// from another workflow. Use it to reset transient state that should not
+// outlive a session.
+//
+// The last-seen session ID is saved in AwGo's cache directory. The result
+// is computed on the first call and doesn't change for the rest of the run.
+//
+// Data that only need to live as long as the session should be stored
+// in Workflow.Session, which is cleared automatically.
+func (wf *Workflow) IsNewSession() bool {
+	if wf.newSession != nil {
+		return *wf.newSession
+	}
+
+	var (
+		c     = NewCache(wf.awCacheDir())
+		name  = "session_id"
+		sid   = wf.SessionID()
+		isNew = true
+	)
+	if data, err := c.Load(name); err == nil {
+		isNew = string(data) != sid
+	}
+	if isNew {
+		if err := c.Store(name, []byte(sid)); err != nil {
+			log.Printf("[ERROR] save session ID: %v", err)
+		}
+	}
+	wf.newSession = &isNew
+	return isNew
+}
+
 // Debug returns true if Alfred's debugger is open.
 func (wf *Workflow) Debug() bool { return wf.Config.GetBool(EnvVarDebug) }
 
+// IsDebug is a synonym for Debug().
+func (wf *Workflow) IsDebug() bool { return wf.Debug() }
+
+// debugf logs a diagnostic message if Alfred's debugger is open.
+func (wf *Workflow) debugf(format string, args ...interface{}) {
+	if wf.Debug() {
+		log.Printf("[debug] "+format, args...)
+	}
+}
+
 // Args returns command-line arguments passed to the program.
 // It intercepts "magic args" and runs the corresponding actions, terminating
 // the workflow. See MagicAction for full documentation.
+//
+// Invalid UTF-8 in the arguments (e.g. from clipboard contents) is
+// replaced with the Unicode replacement character (U+FFFD).
 func (wf *Workflow) Args() []string {
 	prefix := DefaultMagicPrefix
 	if wf.magicPrefix != "" {
 		prefix = wf.magicPrefix
 	}
-	return wf.magicActions.args(os.Args[1:], prefix)
+	return wf.magicActions.args(validUTF8(os.Args[1:]), prefix)
+}
+
+// validUTF8 returns a copy of args with invalid UTF-8 sequences replaced.
+func validUTF8(args []string) []string {
+	valid := make([]string, len(args))
+	for i, s := range args {
+		valid[i] = strings.ToValidUTF8(s, string(utf8.RuneError))
+	}
+	return valid
 }
 
 // Run runs your workflow function, catching any errors.
@@ -358,9 +432,39 @@ func (wf *Workflow) Run(fn func()) {
 	fn()
 
 	wf.Wait()
+	wf.runExitHooks()
 	finishLog(false)
 }
 
+// OnExit registers a function to be called when the workflow exits,
+// e.g. to save state or stop background jobs. Functions are called in
+// reverse order of registration (like deferred functions) when the
+// function passed to Run() returns, when the workflow exits via Fatal()
+// or FatalError(), and after a Magic Action has run.
+//
+// A panic in an exit function is logged, and the remaining functions
+// are still called.
+func (wf *Workflow) OnExit(fn func()) {
+	wf.exitHooks = append(wf.exitHooks, fn)
+}
+
+// runExitHooks calls the functions registered with OnExit. Each function
+// is only called once, even if runExitHooks is called again.
+func (wf *Workflow) runExitHooks() {
+	hooks := wf.exitHooks
+	wf.exitHooks = nil
+	for i := len(hooks) - 1; i >= 0; i-- {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("[ERROR] exit function panicked: %v", r)
+				}
+			}()
+			hooks[i]()
+		}()
+	}
+}
+
 // --------------------------------------------------------------------
 // Helper methods
 
@@ -369,7 +473,7 @@ func (wf *Workflow) outputErrorMsg(msg string) {
 	if wf.textErrors {
 		fmt.Print(msg)
 	} else {
-		wf.Feedback.Clear()
+		wf.Feedback.Items = []*Item{}
 		wf.NewItem(msg).Icon(IconError)
 		wf.SendFeedback()
 	}
@@ -378,6 +482,7 @@ func (wf *Workflow) outputErrorMsg(msg string) {
 	if wf.helpURL != "" {
 		log.Printf("Get help at %s", wf.helpURL)
 	}
+	wf.runExitHooks()
 	finishLog(true)
 }
 
@@ -4,6 +4,7 @@
 package aw
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"path/filepath"
@@ -90,9 +91,12 @@ func (wf *Workflow) Fatalf(format string, args ...interface{}) {
 // Warn displays a warning message in Alfred immediately. Unlike
 // FatalError()/Fatal(), this does not terminate the workflow,
 // but you can't send any more results to Alfred.
+//
+// Any existing items are removed, but top-level variables and rerun
+// are kept.
 func (wf *Workflow) Warn(title, subtitle string) *Workflow {
 	// Remove any existing items
-	wf.Feedback.Clear()
+	wf.Feedback.Items = []*Item{}
 
 	wf.NewItem(title).
 		Subtitle(subtitle).
@@ -131,12 +135,32 @@ func (wf *Workflow) SendFeedback() *Workflow {
 	// Set session ID
 	wf.Var("AW_SESSION_ID", wf.SessionID())
 
+	if wf.emptyTitle != "" && wf.IsEmpty() {
+		wf.debugf("no items, sending warning %q", wf.emptyTitle)
+		wf.NewItem(wf.emptyTitle).
+			Subtitle(wf.emptySub).
+			Icon(IconWarning)
+	}
+
 	// Truncate Items if maxResults is set
 	if wf.maxResults > 0 && len(wf.Feedback.Items) > wf.maxResults {
+		wf.debugf("truncating %d item(s) to %d", len(wf.Feedback.Items), wf.maxResults)
 		wf.Feedback.Items = wf.Feedback.Items[0:wf.maxResults]
 	}
+	wf.Feedback.resolveArgs()
+	wf.debugf("sending feedback: %d item(s), %d variable(s), rerun=%v",
+		len(wf.Feedback.Items), len(wf.Feedback.vars), wf.Feedback.rerun)
+	if wf.Debug() {
+		for _, s := range wf.Feedback.validate() {
+			log.Printf("[warning] %s", s)
+		}
+	}
 
 	if err := wf.Feedback.Send(); err != nil {
+		if errors.Is(err, ErrFeedbackSent) {
+			log.Printf("[ERROR] SendFeedback called more than once: %v", err)
+			return wf
+		}
 		log.Fatalf("Error generating JSON : %v", err)
 	}
 
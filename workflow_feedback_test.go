@@ -4,13 +4,17 @@
 package aw
 
 import (
+	"bytes"
 	"encoding/json"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.deanishe.net/env"
 )
 
 func TestItemHelpers(t *testing.T) {
@@ -56,6 +60,18 @@ func TestNewFileItem(t *testing.T) {
 	assert.Equal(t, ipPath, it.icon.Value, "unexpected icon value")
 }
 
+// Variables are sent even if there are no items.
+func TestWorkflow_emptyFeedbackVars(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		wf.Var("foo", "bar")
+		data, err := json.Marshal(wf.Feedback)
+		require.Nil(t, err, "marshal feedback failed")
+		assert.Equal(t, `{"variables":{"foo":"bar"},"items":[]}`, string(data), "unexpected JSON")
+	})
+}
+
 // WarnEmpty adds an item
 func TestWarnEmpty(t *testing.T) {
 	wf := New()
@@ -63,3 +79,87 @@ func TestWarnEmpty(t *testing.T) {
 	wf.WarnEmpty("test", "test")
 	assert.Equal(t, 1, len(wf.Feedback.Items), "feedback empty")
 }
+
+// Warn replaces items but keeps variables
+func TestWorkflow_Warn(t *testing.T) {
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.Nil(t, err, "open devnull")
+	os.Stdout = devNull
+	defer func() {
+		os.Stdout = stdout
+		panicOnErr(devNull.Close())
+	}()
+
+	withTestWf(func(wf *Workflow) {
+		wf.Var("foo", "bar")
+		wf.Rerun(1)
+		wf.NewItem("item")
+		wf.Warn("warning", "")
+		require.Equal(t, 1, len(wf.Feedback.Items), "unexpected item count")
+		assert.Equal(t, "warning", wf.Feedback.Items[0].title, "unexpected item")
+		assert.Equal(t, "bar", wf.Feedback.Vars()["foo"], "variable not kept")
+		assert.Equal(t, 1.0, wf.Feedback.rerun, "rerun not kept")
+	})
+}
+
+// EmptyWarning adds an item to empty feedback.
+func TestWorkflow_EmptyWarning(t *testing.T) {
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.Nil(t, err, "open devnull")
+	os.Stdout = devNull
+	defer func() {
+		os.Stdout = stdout
+		panicOnErr(devNull.Close())
+	}()
+
+	withTestWf(func(wf *Workflow) {
+		wf.SendFeedback()
+		assert.Equal(t, 0, len(wf.Feedback.Items), "warning added without EmptyWarning")
+
+		wf.Feedback.Reset()
+		wf.Configure(EmptyWarning("No results", "Try again"))
+		wf.NewItem("item")
+		wf.SendFeedback()
+		require.Equal(t, 1, len(wf.Feedback.Items), "unexpected item count")
+		assert.Equal(t, "item", wf.Feedback.Items[0].title, "unexpected item")
+
+		wf.Feedback.Reset()
+		wf.SendFeedback()
+		require.Equal(t, 1, len(wf.Feedback.Items), "warning not added")
+		it := wf.Feedback.Items[0]
+		assert.Equal(t, "No results", it.title, "unexpected title")
+		assert.Equal(t, "Try again", *it.subtitle, "unexpected subtitle")
+		assert.Equal(t, IconWarning, it.icon, "unexpected icon")
+	})
+}
+
+// Diagnostic messages are only logged if debugger is open.
+func TestWorkflow_debugLogging(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.Nil(t, err, "open devnull")
+	os.Stdout = devNull
+	defer func() {
+		os.Stdout = stdout
+		panicOnErr(devNull.Close())
+	}()
+
+	withTestWf(func(wf *Workflow) {
+		require.True(t, wf.IsDebug(), "debugger not open")
+		wf.NewItem("item")
+		wf.SendFeedback()
+		assert.Contains(t, buf.String(), "[debug] sending feedback: 1 item(s)", "no debug message")
+
+		buf.Reset()
+		wf.Config = NewConfig(env.MapEnv{EnvVarDebug: "false"})
+		assert.False(t, wf.IsDebug(), "debugger open")
+		wf.debugf("should not be logged")
+		assert.NotContains(t, buf.String(), "[debug]", "debug message logged")
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/deanishe/awgo/util"
+)
+
+// JXA script to render an SF Symbol to a PNG file.
+// Arguments are symbol name, red, green, blue (0.0-1.0) and output path.
+const jxaRenderSymbol = `ObjC.import('AppKit');
+
+function run(argv) {
+	const name = argv[0],
+		rgb = argv.slice(1, 4).map(parseFloat),
+		path = argv[4]
+
+	let img = $.NSImage.imageWithSystemSymbolNameAccessibilityDescription(name, $())
+	if (img.isNil()) throw new Error('SF Symbol not found: ' + name)
+	img = img.imageWithSymbolConfiguration(
+		$.NSImageSymbolConfiguration.configurationWithPointSizeWeight(128, 0))
+
+	const size = img.size,
+		rect = $.NSMakeRect(0, 0, size.width, size.height),
+		out = $.NSImage.alloc.initWithSize(size)
+
+	out.lockFocus
+	// 2 = NSCompositingOperationSourceOver, 5 = NSCompositingOperationSourceAtop
+	img.drawInRectFromRectOperationFraction(rect, $.NSZeroRect, 2, 1.0)
+	$.NSColor.colorWithSRGBRedGreenBlueAlpha(rgb[0], rgb[1], rgb[2], 1.0).set
+	$.NSRectFillUsingOperation(rect, 5)
+	out.unlockFocus
+
+	// 4 = NSBitmapImageFileTypePNG
+	const data = $.NSBitmapImageRep.imageRepWithData(out.TIFFRepresentation)
+		.representationUsingTypeProperties(4, $())
+	if (!data.writeToFileAtomically(path, true)) throw new Error('could not write ' + path)
+}
+`
+
+// mockable SF Symbol renderer
+var renderSymbol = func(name string, rgb [3]float64, path string) error {
+	args := []string{name}
+	for _, f := range rgb {
+		args = append(args, strconv.FormatFloat(f, 'f', 4, 64))
+	}
+	args = append(args, path)
+	_, err := util.RunJS(jxaRenderSymbol, args...)
+	return err
+}
+
+// SymbolIcon returns an Icon for the SF Symbol name, e.g. "star.fill",
+// rendered in color, which is a hex RGB value such as "#1e90ff" or "fff".
+//
+// The symbol is rendered to a PNG file in the workflow's cache directory
+// the first time it is requested in a given colour. Subsequent calls
+// return the cached file.
+//
+// Rendering requires macOS 11+. An error is returned if color is invalid
+// or the symbol can't be rendered, e.g. because it doesn't exist on the
+// user's version of macOS.
+func (wf *Workflow) SymbolIcon(name, color string) (*Icon, error) {
+	if name == "" {
+		return nil, errors.New("empty symbol name")
+	}
+	rgb, hex, err := parseHexColor(color)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := util.MustExist(filepath.Join(wf.awCacheDir(), "symbols"))
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.png", name, hex))
+	if util.PathExists(path) {
+		return &Icon{Value: path}, nil
+	}
+
+	if err := renderSymbol(name, rgb, path); err != nil {
+		return nil, fmt.Errorf("render SF Symbol %q: %w", name, err)
+	}
+	return &Icon{Value: path}, nil
+}
+
+// parseHexColor parses a "#rrggbb" or "#rgb" colour. It returns the
+// colour's components in the range 0.0-1.0 and its normalised hex value
+// (lowercase "rrggbb").
+func parseHexColor(s string) ([3]float64, string, error) {
+	var rgb [3]float64
+	hex := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return rgb, "", fmt.Errorf("invalid colour %q", s)
+	}
+	for i := range rgb {
+		n, err := strconv.ParseUint(hex[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return rgb, "", fmt.Errorf("invalid colour %q", s)
+		}
+		rgb[i] = float64(n) / 255
+	}
+	return rgb, hex, nil
+}
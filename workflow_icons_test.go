@@ -0,0 +1,90 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHexColor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in    string
+		rgb   [3]float64
+		hex   string
+		valid bool
+	}{
+		{"#ffffff", [3]float64{1, 1, 1}, "ffffff", true},
+		{"000000", [3]float64{0, 0, 0}, "000000", true},
+		{"#F00", [3]float64{1, 0, 0}, "ff0000", true},
+		{" #00ff00 ", [3]float64{0, 1, 0}, "00ff00", true},
+		{"", [3]float64{}, "", false},
+		{"#ff", [3]float64{}, "", false},
+		{"#gggggg", [3]float64{}, "", false},
+		{"blue", [3]float64{}, "", false},
+	}
+
+	for _, td := range tests {
+		td := td
+		t.Run(td.in, func(t *testing.T) {
+			t.Parallel()
+			rgb, hex, err := parseHexColor(td.in)
+			if !td.valid {
+				assert.NotNil(t, err, "accepted invalid colour")
+				return
+			}
+			require.Nil(t, err, "parse colour failed")
+			assert.Equal(t, td.rgb, rgb, "unexpected RGB")
+			assert.Equal(t, td.hex, hex, "unexpected hex")
+		})
+	}
+}
+
+// Not parallel because it replaces renderSymbol.
+func TestWorkflow_SymbolIcon(t *testing.T) {
+	orig := renderSymbol
+	defer func() { renderSymbol = orig }()
+
+	var calls int
+	renderSymbol = func(name string, rgb [3]float64, path string) error {
+		calls++
+		if name == "does.not.exist" {
+			return errors.New("SF Symbol not found")
+		}
+		return ioutil.WriteFile(path, []byte("png"), 0600)
+	}
+
+	withTestWf(func(wf *Workflow) {
+		icon, err := wf.SymbolIcon("star.fill", "#FFCC00")
+		require.Nil(t, err, "SymbolIcon failed")
+		assert.Equal(t, IconTypeImage, icon.Type, "unexpected icon type")
+		assert.Equal(t, "star.fill-ffcc00.png", filepath.Base(icon.Value), "unexpected filename")
+		assert.Equal(t, 1, calls, "symbol not rendered")
+
+		// cached
+		icon2, err := wf.SymbolIcon("star.fill", "ffcc00")
+		require.Nil(t, err, "SymbolIcon failed")
+		assert.Equal(t, icon, icon2, "different icon")
+		assert.Equal(t, 1, calls, "cached symbol rendered again")
+
+		// different colour
+		_, err = wf.SymbolIcon("star.fill", "#000")
+		require.Nil(t, err, "SymbolIcon failed")
+		assert.Equal(t, 2, calls, "symbol not rendered")
+
+		_, err = wf.SymbolIcon("star.fill", "yellow")
+		assert.NotNil(t, err, "accepted invalid colour")
+		_, err = wf.SymbolIcon("", "#fff")
+		assert.NotNil(t, err, "accepted empty name")
+		_, err = wf.SymbolIcon("does.not.exist", "#fff")
+		assert.NotNil(t, err, "rendered invalid symbol")
+	})
+}
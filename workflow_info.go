@@ -0,0 +1,50 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"howett.net/plist"
+)
+
+// Info contains the workflow metadata from its info.plist file.
+type Info struct {
+	Name        string            `plist:"name"`        // Workflow name
+	BundleID    string            `plist:"bundleid"`    // Workflow bundle ID
+	Version     string            `plist:"version"`     // Workflow version
+	Author      string            `plist:"createdby"`   // "Created By" field
+	Description string            `plist:"description"` // Workflow description
+	Website     string            `plist:"webaddress"`  // Workflow website
+	Readme      string            `plist:"readme"`      // "About this Workflow" text
+	Variables   map[string]string `plist:"variables"`   // Workflow configuration sheet variables
+	// Names of variables marked as "Don't Export"
+	NoExport []string `plist:"variablesdontexport"`
+}
+
+// Info returns the workflow's metadata read from the info.plist file in
+// its root directory (see Dir()). The file is only parsed once per run.
+//
+// Unlike the Alfred environment variables, info.plist is also available
+// when the workflow isn't run from Alfred, e.g. from a command-line tool.
+func (wf *Workflow) Info() (*Info, error) {
+	if wf.info != nil {
+		return wf.info, nil
+	}
+
+	path := filepath.Join(wf.Dir(), "info.plist")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read info.plist: %w", err)
+	}
+
+	info := &Info{}
+	if _, err := plist.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("parse info.plist (%s): %w", path, err)
+	}
+	wf.info = info
+	return info, nil
+}
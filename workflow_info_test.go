@@ -0,0 +1,54 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.deanishe.net/env"
+)
+
+// Workflow metadata is read from info.plist
+func TestWorkflow_Info(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		wf.dir = "testdata"
+		info, err := wf.Info()
+		require.Nil(t, err, "read info.plist failed")
+
+		assert.Equal(t, "AwGo", info.Name, "unexpected Name")
+		assert.Equal(t, "net.deanishe.awgo", info.BundleID, "unexpected BundleID")
+		assert.Equal(t, "0.16.1", info.Version, "unexpected Version")
+		assert.Equal(t, "Dean Jackson", info.Author, "unexpected Author")
+		assert.Equal(t, "https://github.com/deanishe/awgo", info.Website, "unexpected Website")
+		assert.Equal(t, map[string]string{
+			"exported_var":   "exported_value",
+			"unexported_var": "unexported_value",
+		}, info.Variables, "unexpected Variables")
+		assert.Equal(t, []string{"unexported_var"}, info.NoExport, "unexpected NoExport")
+
+		// result is cached
+		wf.dir = "invalid"
+		info2, err := wf.Info()
+		require.Nil(t, err, "cached Info failed")
+		assert.True(t, info == info2, "Info not cached")
+	})
+
+	// version falls back to info.plist
+	withTestWf(func(wf *Workflow) {
+		wf.dir = "testdata"
+		wf.Config = NewConfig(env.MapEnv{})
+		assert.Equal(t, "0.16.1", wf.Version(), "unexpected Version")
+	})
+
+	// missing info.plist
+	withTestWf(func(wf *Workflow) {
+		wf.dir = "invalid"
+		_, err := wf.Info()
+		assert.NotNil(t, err, "missing info.plist accepted")
+	})
+}
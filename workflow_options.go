@@ -3,7 +3,11 @@
 
 package aw
 
-import "go.deanishe.net/fuzzy"
+import (
+	"log"
+
+	"go.deanishe.net/fuzzy"
+)
 
 // Option is a configuration option for Workflow.
 // Pass one or more Options to New() or Workflow.Configure().
@@ -38,7 +42,7 @@ func HelpURL(url string) Option {
 		prev := wf.helpURL
 		ma := &helpMA{wf}
 		if url != "" {
-			wf.magicActions.register(ma)
+			wf.magicActions.replace(ma)
 		} else {
 			wf.magicActions.unregister(ma)
 		}
@@ -95,6 +99,20 @@ func MaxResults(num int) Option {
 	}
 }
 
+// EmptyWarning tells SendFeedback to send a warning Item with the given
+// title and subtitle if there are no Items, so the user sees a
+// "No results" message instead of an empty list. Only Items are checked:
+// the warning is also added if variables or rerun are set.
+// An empty title turns the warning off.
+// Default: "" (no warning)
+func EmptyWarning(title, subtitle string) Option {
+	return func(wf *Workflow) Option {
+		prevTitle, prevSub := wf.emptyTitle, wf.emptySub
+		wf.emptyTitle, wf.emptySub = title, subtitle
+		return EmptyWarning(prevTitle, prevSub)
+	}
+}
+
 // TextErrors tells Workflow to print errors as text, not JSON.
 // Messages are still sent to STDOUT. Set to true if error
 // should be captured by Alfred, e.g. if output goes to a Notification.
@@ -106,6 +124,16 @@ func TextErrors(on bool) Option {
 	}
 }
 
+// QueryFlags sets the prefixes of the flags recognised by
+// Workflow.ParseQuery(), e.g. QueryFlags("#", ">").
+func QueryFlags(prefixes ...string) Option {
+	return func(wf *Workflow) Option {
+		prev := wf.queryFlags
+		wf.queryFlags = prefixes
+		return QueryFlags(prev...)
+	}
+}
+
 // SortOptions sets the fuzzy sorting options for Workflow.Filter().
 // See fuzzy and fuzzy.Option for info on (configuring) the sorting
 // algorithm.
@@ -120,6 +148,28 @@ func SortOptions(opts ...fuzzy.Option) Option {
 	}
 }
 
+// MinScore sets the minimum fuzzy score Items must have to be retained
+// by Workflow.Filter(). 0 means retain all matching Items.
+// Default: 0
+func MinScore(score float64) Option {
+	return func(wf *Workflow) Option {
+		prev := wf.Feedback.MinScore
+		wf.Feedback.MinScore = score
+		return MinScore(prev)
+	}
+}
+
+// MatchOrder sets the Item fields Workflow.Filter() matches against.
+// The first non-empty field of each Item is used.
+// Default: FieldMatch, FieldTitle
+func MatchOrder(fields ...MatchField) Option {
+	return func(wf *Workflow) Option {
+		prev := wf.Feedback.MatchOrder
+		wf.Feedback.MatchOrder = fields
+		return MatchOrder(prev...)
+	}
+}
+
 // SessionName changes the name of the variable used to store the session ID.
 //
 // This is useful if you have multiple Script Filters chained together that
@@ -169,10 +219,22 @@ func Update(updater Updater) Option {
 // AddMagic registers Magic Actions with the Workflow.
 // Magic Actions connect special keywords/queries to callback functions.
 // See the MagicAction interface for more information.
+//
+// If an action with the same keyword is already registered, it is replaced
+// and a warning is logged. To catch such mistakes during development,
+// AddMagic panics instead when Alfred's debugger is open. Use
+// Workflow.RegisterMagic to handle collisions yourself, or
+// RemoveMagicKeyword to replace an action deliberately.
 func AddMagic(actions ...MagicAction) Option {
 	return func(wf *Workflow) Option {
 		for _, action := range actions {
-			wf.magicActions.register(action)
+			if err := wf.magicActions.register(action); err != nil {
+				if wf.Debug() {
+					panic(err)
+				}
+				log.Printf("[warning] replaced magic action: %v", err)
+				wf.magicActions.replace(action)
+			}
 		}
 		return RemoveMagic(actions...)
 	}
@@ -187,3 +249,17 @@ func RemoveMagic(actions ...MagicAction) Option {
 		return AddMagic(actions...)
 	}
 }
+
+// RemoveMagicKeyword unregisters the Magic Actions with the given keywords,
+// e.g. to replace a built-in action with your own.
+func RemoveMagicKeyword(keywords ...string) Option {
+	return func(wf *Workflow) Option {
+		var removed []MagicAction
+		for _, kw := range keywords {
+			if action := wf.magicActions.unregisterKeyword(kw); action != nil {
+				removed = append(removed, action)
+			}
+		}
+		return AddMagic(removed...)
+	}
+}
@@ -6,6 +6,7 @@ package aw
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -28,6 +29,12 @@ func (wf *Workflow) Dir() string {
 	return wf.dir
 }
 
+// AlfredPrefsDir returns the path to Alfred's preferences bundle
+// ("Alfred.alfredpreferences"), which is in the user's sync folder if
+// one is set. Returns an empty string if the path isn't set, e.g. when
+// the workflow isn't run from Alfred.
+func (wf *Workflow) AlfredPrefsDir() string { return wf.Config.Get(EnvVarPreferences) }
+
 // CacheDir returns the path to the workflow's cache directory.
 func (wf *Workflow) CacheDir() string {
 	if wf.cacheDir == "" {
@@ -43,8 +50,9 @@ func (wf *Workflow) OpenCache() error {
 }
 
 // ClearCache deletes all files from the workflow's cache directory.
+// It is not an error if the directory doesn't exist.
 func (wf *Workflow) ClearCache() error {
-	return util.ClearDirectory(wf.CacheDir())
+	return Cache{Dir: wf.CacheDir()}.Clear()
 }
 
 // DataDir returns the path to the workflow's data directory.
@@ -67,16 +75,19 @@ func (wf *Workflow) ClearData() error {
 }
 
 // Reset deletes all workflow data (cache and data directories).
+//
+// Both directories are cleared even if clearing one of them fails.
+// The returned error describes all failures and wraps the first one.
 func (wf *Workflow) Reset() error {
-	errs := []error{}
+	var errs []error
 	if err := wf.ClearCache(); err != nil {
-		errs = append(errs, err)
+		errs = append(errs, fmt.Errorf("clear cache directory %q: %w", wf.CacheDir(), err))
 	}
 	if err := wf.ClearData(); err != nil {
-		errs = append(errs, err)
+		errs = append(errs, fmt.Errorf("clear data directory %q: %w", wf.DataDir(), err))
 	}
-	if len(errs) > 0 {
-		return errs[0]
+	if err := joinErrors(errs); err != nil {
+		return fmt.Errorf("reset workflow: %w", err)
 	}
 	return nil
 }
@@ -102,6 +113,79 @@ func (wf *Workflow) OpenHelp() error {
 	return wf.execFunc("open", wf.helpURL)
 }
 
+// Open opens the file at relPath relative to the workflow's root directory
+// (see Dir()), e.g. a data file bundled with the workflow.
+//
+// It returns an error wrapping os.ErrNotExist if the file doesn't exist.
+func (wf *Workflow) Open(relPath string) (*os.File, error) {
+	p, err := wf.bundlePath(relPath)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("open workflow file %q: %w", relPath, err)
+	}
+	return f, nil
+}
+
+// ReadFile returns the contents of the file at relPath relative to the
+// workflow's root directory (see Dir()).
+//
+// It returns an error wrapping os.ErrNotExist if the file doesn't exist.
+func (wf *Workflow) ReadFile(relPath string) ([]byte, error) {
+	p, err := wf.bundlePath(relPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("read workflow file %q: %w", relPath, err)
+	}
+	return data, nil
+}
+
+// bundlePath returns relPath joined to the workflow's root directory.
+// Absolute paths and paths outside the root directory, e.g. "../file",
+// are rejected, as the file wouldn't be in the workflow.
+func (wf *Workflow) bundlePath(relPath string) (string, error) {
+	p := filepath.Clean(relPath)
+	if relPath == "" || filepath.IsAbs(p) ||
+		p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid workflow file path %q: must be relative to %q", relPath, wf.Dir())
+	}
+	return filepath.Join(wf.Dir(), p), nil
+}
+
+// ResolvePath returns the absolute path of p with a leading "~" expanded
+// to the user's home directory and any symlinks resolved, e.g. to check a
+// path passed to the workflow as its query. Relative paths are relative
+// to the working directory.
+//
+// It returns an error wrapping os.ErrNotExist if the path doesn't exist.
+func (wf *Workflow) ResolvePath(p string) (string, error) {
+	if p == "" {
+		return "", errors.New("empty path")
+	}
+	orig := p
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("expand path %q: %w", orig, err)
+		}
+		p = filepath.Join(home, p[1:])
+	}
+
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return "", fmt.Errorf("resolve path %q: %w", orig, err)
+	}
+	if p, err = filepath.EvalSymlinks(p); err != nil {
+		return "", fmt.Errorf("resolve path %q: %w", orig, err)
+	}
+	return p, nil
+}
+
 // Try to find workflow root based on presence of info.plist.
 func findWorkflowRoot(path string) string {
 	var (
@@ -4,11 +4,17 @@
 package aw
 
 import (
+	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.deanishe.net/env"
+
+	"github.com/deanishe/awgo/util"
 )
 
 func TestReset(t *testing.T) {
@@ -39,6 +45,134 @@ func TestReset(t *testing.T) {
 	})
 }
 
+// Reset clears the data directory even if clearing the cache fails.
+func TestReset_partial(t *testing.T) {
+	withTestWf(func(wf *Workflow) {
+		name := "xyz.json"
+		require.Nil(t, wf.Data.Store(name, []byte("muh bytes")), "data store failed")
+
+		// a file can't be cleared like a directory
+		p := filepath.Join(wf.CacheDir(), "file.txt")
+		require.Nil(t, ioutil.WriteFile(p, []byte("test"), 0600), "write file failed")
+		wf.cacheDir = p
+
+		err := wf.Reset()
+		require.NotNil(t, err, "reset succeeded")
+		assert.Contains(t, err.Error(), "clear cache directory", "unexpected error")
+		assert.NotContains(t, err.Error(), "clear data directory", "unexpected error")
+		assert.False(t, wf.Data.Exists(name), "data not cleared")
+	})
+}
+
+// ClearCache deletes cache files and ignores a missing directory.
+func TestWorkflow_ClearCache(t *testing.T) {
+	withTestWf(func(wf *Workflow) {
+		p := filepath.Join(wf.CacheDir(), "test.txt")
+		require.Nil(t, ioutil.WriteFile(p, []byte("test"), 0600), "write file failed")
+		require.Nil(t, wf.ClearCache(), "clear cache failed")
+		assert.False(t, util.PathExists(p), "cache file not deleted")
+
+		require.Nil(t, os.RemoveAll(wf.CacheDir()), "delete cache directory failed")
+		assert.NotPanics(t, func() {
+			assert.Nil(t, wf.ClearCache(), "clear missing cache failed")
+		}, "ClearCache panicked")
+		assert.False(t, util.PathExists(wf.CacheDir()), "cache directory created")
+	})
+}
+
+func TestAlfredPrefsDir(t *testing.T) {
+	withTestWf(func(wf *Workflow) {
+		assert.Equal(t, tPreferences, wf.AlfredPrefsDir(), "unexpected preferences dir")
+
+		wf = NewFromEnv(env.MapEnv{
+			EnvVarBundleID: tBundleID,
+			EnvVarCacheDir: wf.CacheDir(),
+			EnvVarDataDir:  wf.DataDir(),
+		})
+		assert.Equal(t, "", wf.AlfredPrefsDir(), "unexpected preferences dir")
+	})
+}
+
+// Paths are expanded and resolved
+func TestWorkflow_ResolvePath(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		var (
+			dir  = wf.DataDir()
+			file = filepath.Join(dir, "file.txt")
+			link = filepath.Join(dir, "link.txt")
+		)
+		require.Nil(t, ioutil.WriteFile(file, []byte("test"), 0600), "write file failed")
+		require.Nil(t, os.Symlink(file, link), "create symlink failed")
+
+		home, err := os.UserHomeDir()
+		require.Nil(t, err, "get home directory failed")
+		home, err = filepath.EvalSymlinks(home)
+		require.Nil(t, err, "resolve home directory failed")
+		cwd, err := os.Getwd()
+		require.Nil(t, err, "Getwd failed")
+
+		tests := []struct {
+			in, x string
+		}{
+			{file, file},
+			{link, file},
+			{dir + "/../data/./file.txt", file},
+			{"~", home},
+			{"testdata", filepath.Join(cwd, "testdata")},
+		}
+		for _, td := range tests {
+			v, err := wf.ResolvePath(td.in)
+			require.Nil(t, err, "resolve %q failed", td.in)
+			assert.Equal(t, td.x, v, "unexpected path for %q", td.in)
+		}
+
+		_, err = wf.ResolvePath(filepath.Join(dir, "missing.txt"))
+		assert.True(t, errors.Is(err, os.ErrNotExist), "missing file not reported")
+		_, err = wf.ResolvePath("")
+		assert.NotNil(t, err, "empty path accepted")
+	})
+}
+
+func TestWorkflow_ReadFile(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		wf.dir = "testdata"
+		x, err := ioutil.ReadFile("testdata/info.plist")
+		require.Nil(t, err, "read info.plist failed")
+
+		data, err := wf.ReadFile("info.plist")
+		require.Nil(t, err, "ReadFile failed")
+		assert.Equal(t, x, data, "unexpected file contents")
+
+		f, err := wf.Open("info.plist")
+		require.Nil(t, err, "Open failed")
+		defer f.Close()
+		data, err = ioutil.ReadAll(f)
+		require.Nil(t, err, "read file failed")
+		assert.Equal(t, x, data, "unexpected file contents")
+
+		_, err = wf.ReadFile("missing.csv")
+		assert.True(t, errors.Is(err, os.ErrNotExist), "missing file not reported")
+		_, err = wf.Open("missing.csv")
+		assert.True(t, errors.Is(err, os.ErrNotExist), "missing file not reported")
+
+		for _, s := range []string{"", "/etc/hosts", "..", "../info.plist", "sub/../../info.plist"} {
+			_, err = wf.ReadFile(s)
+			assert.NotNil(t, err, "invalid path %q accepted", s)
+		}
+		// paths that stay inside the workflow are cleaned
+		p, err := wf.bundlePath("sub/../info.plist")
+		require.Nil(t, err, "valid path rejected")
+		assert.Equal(t, filepath.Join("testdata", "info.plist"), p, "unexpected path")
+		p, err = wf.bundlePath("..info.plist")
+		require.Nil(t, err, "valid path rejected")
+		assert.Equal(t, filepath.Join("testdata", "..info.plist"), p, "unexpected path")
+	})
+}
+
 func TestWorkflowRoot(t *testing.T) {
 	withTestWf(func(wf *Workflow) {
 		wd, err := os.Getwd()
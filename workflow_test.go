@@ -12,6 +12,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -52,6 +53,12 @@ func TestNew(t *testing.T) {
 			MaxResults(10),
 			func(wf *Workflow) bool { return wf.maxResults == 10 },
 			"Set MaxResults"},
+		{
+			EmptyWarning("No results", "Try a different query"),
+			func(wf *Workflow) bool {
+				return wf.emptyTitle == "No results" && wf.emptySub == "Try a different query"
+			},
+			"Set EmptyWarning"},
 		{
 			LogPrefix("blah"),
 			func(wf *Workflow) bool { return wf.logPrefix == "blah" },
@@ -64,6 +71,12 @@ func TestNew(t *testing.T) {
 			SortOptions(),
 			func(wf *Workflow) bool { return wf.sortOptions == nil },
 			"Set SortOptions"},
+		{
+			MatchOrder(FieldSubtitle),
+			func(wf *Workflow) bool {
+				return len(wf.Feedback.MatchOrder) == 1 && wf.Feedback.MatchOrder[0] == FieldSubtitle
+			},
+			"Set MatchOrder"},
 		{
 			SuppressUIDs(true),
 			func(wf *Workflow) bool { return wf.Feedback.NoUIDs == true },
@@ -80,6 +93,10 @@ func TestNew(t *testing.T) {
 			TextErrors(true),
 			func(wf *Workflow) bool { return wf.textErrors == true },
 			"Set TextErrors"},
+		{
+			QueryFlags("#", ">"),
+			func(wf *Workflow) bool { return len(wf.queryFlags) == 2 && wf.queryFlags[0] == "#" },
+			"Set QueryFlags"},
 		{
 			AddMagic(&mockMA{}),
 			func(wf *Workflow) bool { return wf.magicActions.actions["test"] != nil },
@@ -88,6 +105,12 @@ func TestNew(t *testing.T) {
 			RemoveMagic(logMA{}),
 			func(wf *Workflow) bool { return wf.magicActions.actions["log"] == nil },
 			"Remove Magic"},
+		{
+			RemoveMagicKeyword("log", "cache"),
+			func(wf *Workflow) bool {
+				return wf.magicActions.actions["log"] == nil && wf.magicActions.actions["cache"] == nil
+			},
+			"Remove Magic Keyword"},
 	}
 
 	for _, td := range tests {
@@ -204,6 +227,56 @@ func TestWorkflow_Vars(t *testing.T) {
 	})
 }
 
+// New sessions are detected
+func TestWorkflow_IsNewSession(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		wf.sessionID = "session1"
+		assert.True(t, wf.IsNewSession(), "first run is not new session")
+		assert.True(t, wf.IsNewSession(), "result changed during run")
+
+		// next run in same session
+		wf.newSession = nil
+		assert.False(t, wf.IsNewSession(), "same session is new")
+
+		// run in a new session
+		wf.newSession = nil
+		wf.sessionID = "session2"
+		assert.True(t, wf.IsNewSession(), "different session is not new")
+	})
+}
+
+// Exit functions are called in LIFO order
+func TestWorkflow_OnExit(t *testing.T) {
+	withTestWf(func(wf *Workflow) {
+		var called []int
+		wf.OnExit(func() { called = append(called, 1) })
+		wf.OnExit(func() { panic("exit function failed") })
+		wf.OnExit(func() { called = append(called, 3) })
+
+		wf.Run(func() { assert.Nil(t, called, "exit function called early") })
+		assert.Equal(t, []int{3, 1}, called, "unexpected exit function calls")
+
+		// functions are only called once
+		wf.runExitHooks()
+		assert.Equal(t, []int{3, 1}, called, "exit functions called again")
+	})
+
+	// called on fatal error
+	withTestWf(func(wf *Workflow) {
+		me := &mockExit{}
+		exitFunc = me.Exit
+		defer func() { exitFunc = os.Exit }()
+
+		var called bool
+		wf.OnExit(func() { called = true })
+		wf.Fatal("die")
+		assert.True(t, called, "exit function not called by Fatal")
+		assert.Equal(t, 1, me.code, "workflow did not exit")
+	})
+}
+
 func TestWorkflow_Rerun(t *testing.T) {
 	t.Parallel()
 
@@ -354,3 +427,20 @@ func ExampleArgVars() {
 	}
 	// Output: {"alfredworkflow":{"arg":"baz","variables":{"foo":"bar"}}}
 }
+
+// Invalid UTF-8 is removed from arguments and feedback.
+func TestWorkflow_ArgsInvalidUTF8(t *testing.T) {
+	withTestWf(func(wf *Workflow) {
+		args := os.Args
+		defer func() { os.Args = args }()
+		os.Args = []string{"workflow", "caf\xe9", "ok"}
+
+		v := wf.Args()
+		assert.Equal(t, []string{"caf\ufffd", "ok"}, v, "unexpected args")
+
+		data, err := json.Marshal(wf.NewItem("caf\xe9").Arg(v[0]))
+		require.Nil(t, err, "marshal item failed")
+		assert.True(t, utf8.Valid(data), "invalid UTF-8 in JSON")
+		assert.Equal(t, "{\"title\":\"caf\ufffd\",\"arg\":\"caf\ufffd\",\"valid\":false}", string(data), "unexpected JSON")
+	})
+}
@@ -23,7 +23,7 @@ type Updater interface {
 // setUpdater sets an updater for the workflow.
 func (wf *Workflow) setUpdater(u Updater) {
 	wf.Updater = u
-	wf.magicActions.register(&updateMA{wf.Updater})
+	wf.magicActions.replace(&updateMA{wf.Updater}, &checkUpdateMA{updater: wf.Updater, notify: wf.SendNotification})
 }
 
 // UpdateCheckDue returns true if an update is available.
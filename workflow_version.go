@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/deanishe/awgo/internal/semver"
+)
+
+// IsNewerThan returns true if the workflow's version (see Version()) is
+// newer than the semantic version v, e.g. "1.2.0" or "v2.0.0-beta.1".
+// It returns false if either version is missing or invalid.
+func (wf *Workflow) IsNewerThan(v string) bool {
+	current, err := parseVersion(wf.Version())
+	if err != nil {
+		log.Printf("[warning] workflow version: %v", err)
+		return false
+	}
+	other, err := parseVersion(v)
+	if err != nil {
+		log.Printf("[warning] %v", err)
+		return false
+	}
+	return current.Compare(other) > 0
+}
+
+// PreviousVersion returns the version of the workflow (see Version()) that
+// was run before this one, or an empty string if the workflow hasn't been
+// run before. Use it with IsNewerThan to run one-time migrations after
+// the user has updated the workflow:
+//
+//	if prev := wf.PreviousVersion(); prev != "" && wf.IsNewerThan(prev) {
+//		// migrate data from version prev
+//	}
+//
+// The version is saved in AwGo's data directory. The result is computed
+// on the first call and doesn't change for the rest of the run.
+func (wf *Workflow) PreviousVersion() string {
+	if wf.prevVersion != nil {
+		return *wf.prevVersion
+	}
+
+	var (
+		c       = NewCache(wf.awDataDir())
+		name    = "version"
+		current = wf.Version()
+		prev    string
+	)
+	if data, err := c.Load(name); err == nil {
+		prev = string(data)
+	}
+	if current != "" && current != prev {
+		if err := c.Store(name, []byte(current)); err != nil {
+			log.Printf("[ERROR] save workflow version: %v", err)
+		}
+	}
+	wf.prevVersion = &prev
+	return prev
+}
+
+// parseVersion parses a semantic version number. Missing minor and patch
+// numbers are treated as 0, and a leading "v" and build metadata are ignored.
+// Package update has a fully featured SemVer type, but it imports this package.
+func parseVersion(s string) (semver.Version, error) {
+	var (
+		v    semver.Version
+		orig = s
+	)
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i != -1 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i != -1 {
+		s, v.Prerelease = s[:i], s[i+1:]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q: more than 3 parts", orig)
+	}
+	nums := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return v, fmt.Errorf("invalid version %q: %q is not a number", orig, p)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
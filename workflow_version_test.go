@@ -0,0 +1,87 @@
+// Copyright (c) 2020 Dean Jackson <deanishe@deanishe.net>
+// MIT Licence - http://opensource.org/licenses/MIT
+
+package aw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.deanishe.net/env"
+)
+
+func TestVersion_compare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		x    int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1", "1.0.0", 0},
+		{"1.0.0+build.1", "1.0.0", 0},
+		{"1.0.1", "1.0.0", 1},
+		{"1.10", "1.9", 1},
+		{"2.0", "10.0", -1},
+		{"1.0.0-beta", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.10", -1},
+		{"1.0.0-beta", "1.0.0-beta.1", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+	}
+	for _, td := range tests {
+		a, err := parseVersion(td.a)
+		require.Nil(t, err, "parse %q failed", td.a)
+		b, err := parseVersion(td.b)
+		require.Nil(t, err, "parse %q failed", td.b)
+		assert.Equal(t, td.x, a.Compare(b), "unexpected result for %q vs %q", td.a, td.b)
+		assert.Equal(t, -td.x, b.Compare(a), "unexpected result for %q vs %q", td.b, td.a)
+	}
+
+	for _, s := range []string{"", "one", "1.2.3.4", "1.x", "-beta"} {
+		_, err := parseVersion(s)
+		assert.NotNil(t, err, "invalid version %q accepted", s)
+	}
+}
+
+func TestWorkflow_IsNewerThan(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		require.Equal(t, tVersion, wf.Version(), "unexpected version")
+		assert.True(t, wf.IsNewerThan("1.1.9"), "1.2.0 not newer than 1.1.9")
+		assert.True(t, wf.IsNewerThan("1.2.0-beta"), "1.2.0 not newer than 1.2.0-beta")
+		assert.False(t, wf.IsNewerThan("1.2.0"), "1.2.0 newer than itself")
+		assert.False(t, wf.IsNewerThan("v2"), "1.2.0 newer than 2.0.0")
+		assert.False(t, wf.IsNewerThan("invalid"), "newer than invalid version")
+	})
+}
+
+func TestWorkflow_PreviousVersion(t *testing.T) {
+	t.Parallel()
+
+	withTestWf(func(wf *Workflow) {
+		assert.Equal(t, "", wf.PreviousVersion(), "unexpected version on first run")
+
+		// same version
+		wf.prevVersion = nil
+		assert.Equal(t, tVersion, wf.PreviousVersion(), "unexpected previous version")
+
+		// workflow updated
+		e := env.MapEnv{}
+		for _, k := range wf.Config.keys() {
+			e[k], _ = wf.Config.Lookup(k)
+		}
+		e[EnvVarVersion] = "2.0.0"
+		wf.Config = NewConfig(e)
+		wf.prevVersion = nil
+		assert.Equal(t, tVersion, wf.PreviousVersion(), "unexpected previous version")
+		assert.True(t, wf.IsNewerThan(wf.PreviousVersion()), "updated version not newer")
+
+		// result doesn't change during run
+		assert.Equal(t, tVersion, wf.PreviousVersion(), "cached version changed")
+		wf.prevVersion = nil
+		assert.Equal(t, "2.0.0", wf.PreviousVersion(), "new version not saved")
+	})
+}